@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// actorFields are the bead fields bd uses to record who changed a bead, most
+// recent change first. They are distinct from assignee: the assignee owns a
+// bead, the actor is whoever last touched it. Older bd versions record none
+// of them.
+var actorFields = []string{"updated_by", "closed_by", "created_by"}
+
+// beadActor returns the bead's most recent actor and whether bd recorded
+// any actor field at all.
+func beadActor(bead map[string]any) (actor string, known bool) {
+	for _, f := range actorFields {
+		if v, ok := bead[f]; ok {
+			known = true
+			if s, _ := v.(string); s != "" {
+				return s, true
+			}
+		}
+	}
+	return "", known
+}
+
+// handleActivity lists beads last touched by ?actor=, newest update first,
+// capped by ?limit= (default 50). It needs bd to include actor metadata
+// (updated_by/closed_by/created_by) in `bd list --json`; when no bead
+// carries any, it answers 501 rather than an empty list.
+func handleActivity(w http.ResponseWriter, r *http.Request) {
+	actor := r.URL.Query().Get("actor")
+	if actor == "" {
+		sendError(w, "actor is required", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			sendError(w, fmt.Sprintf("invalid limit %q", s), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	ctx, cancel := withBeadsBudget(r.Context())
+	defer cancel()
+	dirs, _ := limitDirs(beadDirs(), cfg.MaxRigs)
+	raw, errs, _ := fetchBeads(ctx, dirs, nil, beadsTTL(cfg))
+
+	type touched struct {
+		raw     json.RawMessage
+		updated time.Time
+	}
+	var matches []touched
+	sawActor := false
+	for _, data := range raw {
+		var bead map[string]any
+		if json.Unmarshal(data, &bead) != nil {
+			continue
+		}
+		who, known := beadActor(bead)
+		sawActor = sawActor || known
+		if who != actor {
+			continue
+		}
+		updated, _ := bead["updated_at"].(string)
+		t, _ := time.Parse(time.RFC3339, updated)
+		matches = append(matches, touched{data, t})
+	}
+	if len(raw) > 0 && !sawActor {
+		sendError(w, "this bd version does not record who changed a bead", http.StatusNotImplemented)
+		return
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].updated.After(matches[j].updated) })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	beads := make([]json.RawMessage, len(matches))
+	for i, m := range matches {
+		beads[i] = m.raw
+	}
+	sendBeads(w, beads, errs, nil, r.URL.Query().Get("verbose") == "1")
+}
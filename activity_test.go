@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleActivity(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `echo '[
+ {"id":"ri-1","assignee":"alice","created_by":"alice","updated_by":"bob","updated_at":"2026-01-01T00:00:00Z"},
+ {"id":"ri-2","assignee":"bob","updated_by":"alice","updated_at":"2026-02-01T00:00:00Z"},
+ {"id":"ri-3","created_by":"alice","updated_at":"2026-03-01T00:00:00Z"}
+]'`)
+
+	w := httptest.NewRecorder()
+	handleActivity(w, httptest.NewRequest("GET", "/api/beads/activity?actor=alice", nil))
+	var beads []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &beads); err != nil {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	// ri-1 was created by alice but last changed by bob.
+	if len(beads) != 2 || beads[0].ID != "ri-3" || beads[1].ID != "ri-2" {
+		t.Errorf("beads = %+v, want ri-3, ri-2", beads)
+	}
+
+	w = httptest.NewRecorder()
+	handleActivity(w, httptest.NewRequest("GET", "/api/beads/activity", nil))
+	if w.Code != 400 {
+		t.Errorf("missing actor status = %d, want 400", w.Code)
+	}
+}
+
+func TestHandleActivityWithoutActorMetadata(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","assignee":"alice"}]'`)
+
+	w := httptest.NewRecorder()
+	handleActivity(w, httptest.NewRequest("GET", "/api/beads/activity?actor=alice", nil))
+	if w.Code != 501 {
+		t.Errorf("status = %d, want 501", w.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// adminToken guards /api/admin/*. main reads it from RIGRADAR_ADMIN_TOKEN
+// rather than config.json, which /api/config hands to every client. Empty
+// disables the admin endpoints.
+var adminToken string
+
+// draining is set by POST /api/admin/drain: /readyz answers 503 so a load
+// balancer stops routing here, and responses ask clients to close their
+// connections. Requests keep being served.
+var draining atomic.Bool
+
+// inFlight counts requests being served, so a draining operator can tell
+// when it is safe to stop the process.
+var inFlight atomic.Int64
+
+// trackInFlight maintains inFlight and, while draining, sets
+// Connection: close so keep-alive clients reconnect elsewhere.
+func trackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if draining.Load() {
+			w.Header().Set("Connection", "close")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAdmin only lets requests carrying "Authorization: Bearer
+// <adminToken>" through.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			sendError(w, "admin endpoints are disabled; set RIGRADAR_ADMIN_TOKEN", http.StatusForbidden)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			sendError(w, "missing or wrong admin token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// otherInFlight is inFlight without the request asking for it.
+func otherInFlight() int64 {
+	return max(inFlight.Load()-1, 0)
+}
+
+// handleDrain starts draining and reports how many other requests are
+// still being served.
+func handleDrain(w http.ResponseWriter, r *http.Request) {
+	draining.Store(true)
+	sendJSON(w, map[string]any{"draining": true, "inFlight": otherInFlight()}, http.StatusOK)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDrain(t *testing.T) {
+	origRoot, origToken := townRoot, adminToken
+	defer func() { townRoot, adminToken = origRoot, origToken }()
+	townRoot = t.TempDir()
+	fakeBin(t, "bd", "true")
+	defer draining.Store(false)
+	h := buildHandler()
+
+	drain := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/admin/drain", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	adminToken = ""
+	if w := drain("Bearer anything"); w.Code != 403 {
+		t.Errorf("without a configured token status = %d, want 403", w.Code)
+	}
+	adminToken = "s3cret"
+	if w := drain(""); w.Code != 401 {
+		t.Errorf("without Authorization status = %d, want 401", w.Code)
+	}
+	if w := drain("Bearer wrong"); w.Code != 401 {
+		t.Errorf("wrong token status = %d, want 401", w.Code)
+	}
+	if draining.Load() {
+		t.Fatal("rejected requests must not start draining")
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != 200 {
+		t.Fatalf("readyz before drain = %d, want 200", w.Code)
+	}
+
+	if w := drain("Bearer s3cret"); w.Code != 200 || !strings.Contains(w.Body.String(), `"inFlight":0`) {
+		t.Fatalf("drain = %d %s, want 200 with no other requests in flight", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != 503 || !strings.Contains(w.Body.String(), `"draining"`) {
+		t.Errorf("readyz while draining = %d %s, want 503 draining", w.Code, w.Body)
+	}
+	if w.Header().Get("Connection") != "close" {
+		t.Error("responses while draining should ask clients to close the connection")
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+	if w.Code != 200 {
+		t.Errorf("livez while draining = %d, want 200: draining still serves requests", w.Code)
+	}
+}
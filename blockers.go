@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// blocker is one entry of /api/bead/{id}/blockers. Blocking is false once
+// the blocker is closed.
+type blocker struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Blocking bool   `json:"blocking"`
+	Error    string `json:"error,omitempty"`
+}
+
+// showBead runs `bd show id --json` in the id's beads dir and decodes the
+// bead. bd prints either the bead or a one-element array.
+func showBead(r *http.Request, id string) (map[string]any, error) {
+	data, err := execCmdContext(r.Context(), "bd", []string{"show", id, "--json"}, map[string]string{"BEADS_DIR": beadsDirForID(id)})
+	if err != nil {
+		return nil, err
+	}
+	var list []map[string]any
+	if json.Unmarshal(data, &list) == nil && len(list) > 0 {
+		return list[0], nil
+	}
+	var bead map[string]any
+	if err := json.Unmarshal(data, &bead); err != nil {
+		return nil, err
+	}
+	return bead, nil
+}
+
+// blockerIDs returns the ids of the bead's "blocks" dependencies. Both
+// bd's flat {depends_on_id, type} entries and the embedded
+// {id, dependency_type} form are understood.
+func blockerIDs(bead map[string]any) []string {
+	deps, _ := bead["dependencies"].([]any)
+	var ids []string
+	for _, d := range deps {
+		dep, ok := d.(map[string]any)
+		if !ok {
+			continue
+		}
+		typ, _ := dep["type"].(string)
+		if typ == "" {
+			typ, _ = dep["dependency_type"].(string)
+		}
+		if typ != "" && typ != "blocks" {
+			continue
+		}
+		id, _ := dep["depends_on_id"].(string)
+		if id == "" {
+			id, _ = dep["id"].(string)
+		}
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func handleBlockers(w http.ResponseWriter, r *http.Request) {
+	bead, err := showBead(r, r.PathValue("id"))
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	ids := blockerIDs(bead)
+	blockers := make([]blocker, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			b := blocker{ID: id, Blocking: true}
+			if dep, err := showBead(r, id); err != nil {
+				b.Error = err.Error()
+			} else {
+				b.Title, _ = dep["title"].(string)
+				b.Status, _ = dep["status"].(string)
+				b.Blocking = b.Status != "closed"
+			}
+			blockers[i] = b
+		}(i, id)
+	}
+	wg.Wait()
+	sendJSON(w, blockers, http.StatusOK)
+}
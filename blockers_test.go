@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleBlockers(t *testing.T) {
+	origMap, origRoot := prefixMap, townRoot
+	defer func() { prefixMap, townRoot = origMap, origRoot }()
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"hq": filepath.Join(townRoot, ".beads"), "ri": filepath.Join(townRoot, "rigradar", ".beads")}
+
+	fakeBin(t, "bd", `case "$2" in
+ri-1) echo '[{"id":"ri-1","dependencies":[
+  {"issue_id":"ri-1","depends_on_id":"ri-2","type":"blocks"},
+  {"issue_id":"ri-1","depends_on_id":"hq-3","type":"blocks"},
+  {"issue_id":"ri-1","depends_on_id":"ri-9","type":"parent-child"}]}]' ;;
+ri-2) echo '{"id":"ri-2","title":"Open blocker","status":"open"}' ;;
+hq-3) echo '{"id":"hq-3","title":"Done blocker","status":"closed"}' ;;
+ri-4) echo '{"id":"ri-4","title":"Free"}' ;;
+*) echo "not found" >&2; exit 1 ;;
+esac
+`)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/bead/ri-1/blockers", nil)
+	req.SetPathValue("id", "ri-1")
+	handleBlockers(w, req)
+	if w.Code != 200 {
+		t.Fatalf("blockers status = %d, body: %s", w.Code, w.Body)
+	}
+	var blockers []blocker
+	json.Unmarshal(w.Body.Bytes(), &blockers)
+	if len(blockers) != 2 {
+		t.Fatalf("got %d blockers, want 2 (parent-child is not a blocker): %s", len(blockers), w.Body)
+	}
+	if b := blockers[0]; b.ID != "ri-2" || b.Title != "Open blocker" || !b.Blocking {
+		t.Errorf("first blocker = %+v, want open ri-2 still blocking", b)
+	}
+	if b := blockers[1]; b.ID != "hq-3" || b.Status != "closed" || b.Blocking {
+		t.Errorf("second blocker = %+v, want closed hq-3 no longer blocking", b)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/bead/ri-4/blockers", nil)
+	req.SetPathValue("id", "ri-4")
+	handleBlockers(w, req)
+	if body := w.Body.String(); body != "[]\n" {
+		t.Errorf("bead without blockers = %q, want []", body)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type bulkCloseRequest struct {
+	IDs    []string `json:"ids"`
+	Reason string   `json:"reason"`
+	// Confirm must equal len(IDs) once more than Config.BulkConfirmAbove
+	// ids are sent, unless Force is set.
+	Confirm int  `json:"confirm"`
+	Force   bool `json:"force"`
+}
+
+// bulkFailure reports ids whose bd close call failed.
+type bulkFailure struct {
+	IDs      []string `json:"ids"`
+	Error    string   `json:"error"`
+	ExitCode *int     `json:"exitCode,omitempty"`
+}
+
+// handleBulkClose closes several beads, running one `bd close` per beads
+// directory.
+func handleBulkClose(w http.ResponseWriter, r *http.Request) {
+	var body bulkCloseRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids := uniqueIDs(body.IDs)
+	if len(ids) == 0 {
+		sendError(w, "no bead ids given", http.StatusBadRequest)
+		return
+	}
+	for _, id := range ids {
+		if !validBeadID(id) {
+			sendError(w, fmt.Sprintf("invalid bead id %q", id), http.StatusBadRequest)
+			return
+		}
+	}
+	reason := strings.TrimSpace(body.Reason)
+
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	if cfg.RequireCloseReason && reason == "" {
+		sendError(w, "a close reason is required", http.StatusBadRequest)
+		return
+	}
+	if len(ids) > cfg.BulkConfirmAbove && !body.Force && body.Confirm != len(ids) {
+		sendErrorDetails(w, fmt.Sprintf("closing %d beads needs confirmation: send \"confirm\": %d", len(ids), len(ids)),
+			http.StatusBadRequest, map[string]any{"expectedConfirm": len(ids)})
+		return
+	}
+
+	byDir := make(map[string][]string)
+	for _, id := range ids {
+		dir := beadsDirForID(id)
+		byDir[dir] = append(byDir[dir], id)
+	}
+
+	closed := []string{}
+	failures := []bulkFailure{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for dir, group := range byDir {
+		wg.Add(1)
+		go func(dir string, group []string) {
+			defer wg.Done()
+			args := append([]string{"close"}, group...)
+			args = append(args, "--json")
+			if reason != "" {
+				args = append(args, "--reason="+reason)
+			}
+			// Detached from r.Context() like the single close.
+			_, err := execCmd("bd", args, map[string]string{"BEADS_DIR": dir})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				f := bulkFailure{IDs: group, Error: err.Error()}
+				if code, ok := exitCode(err); ok {
+					f.ExitCode = &code
+				}
+				failures = append(failures, f)
+				return
+			}
+			closed = append(closed, group...)
+		}(dir, group)
+	}
+	wg.Wait()
+
+	sort.Strings(closed)
+	status := http.StatusOK
+	if len(closed) == 0 {
+		status = http.StatusInternalServerError
+	}
+	sendJSON(w, map[string]any{"closed": closed, "errors": failures}, status)
+}
+
+// uniqueIDs trims ids and drops blanks and duplicates, keeping order.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	var out []string
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBulkCloseConfirmation(t *testing.T) {
+	origPath, origMap, origRoot := configPath, prefixMap, townRoot
+	defer func() { configPath, prefixMap, townRoot = origPath, origMap, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"hq": filepath.Join(townRoot, ".beads"), "ri": filepath.Join(townRoot, "rigradar", ".beads")}
+	cfg := loadConfig()
+	cfg.BulkConfirmAbove = 2
+	saveConfig(cfg)
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeBin(t, "bd", `echo "$@" >> `+calls+`
+echo '[]'
+`)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handleBulkClose(w, httptest.NewRequest("POST", "/api/beads/close", strings.NewReader(body)))
+		return w
+	}
+
+	// Unconfirmed: nothing runs.
+	w := post(`{"ids":["ri-1","ri-2","hq-3"]}`)
+	if w.Code != 400 {
+		t.Fatalf("unconfirmed status = %d, want 400", w.Code)
+	}
+	var errBody map[string]any
+	json.Unmarshal(w.Body.Bytes(), &errBody)
+	if errBody["expectedConfirm"] != 3.0 {
+		t.Errorf("expectedConfirm = %v, want 3", errBody["expectedConfirm"])
+	}
+	if w := post(`{"ids":["ri-1","ri-2","hq-3"],"confirm":2}`); w.Code != 400 {
+		t.Errorf("wrong confirm count status = %d, want 400", w.Code)
+	}
+	if _, err := os.Stat(calls); err == nil {
+		t.Fatal("bd ran for an unconfirmed bulk close")
+	}
+
+	// Confirmed: one bd close per beads dir.
+	w = post(`{"ids":["ri-1","ri-2","hq-3"],"confirm":3,"reason":"done"}`)
+	if w.Code != 200 {
+		t.Fatalf("confirmed status = %d, body: %s", w.Code, w.Body)
+	}
+	var result struct {
+		Closed []string `json:"closed"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if strings.Join(result.Closed, ",") != "hq-3,ri-1,ri-2" {
+		t.Errorf("closed = %v", result.Closed)
+	}
+	data, _ := os.ReadFile(calls)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("bd calls = %q, want one per dir", data)
+	}
+
+	// At or below the threshold no confirmation is needed; force skips it.
+	if w := post(`{"ids":["ri-1","ri-2"]}`); w.Code != 200 {
+		t.Errorf("small bulk close status = %d, want 200", w.Code)
+	}
+	if w := post(`{"ids":["ri-1","ri-2","ri-3"],"force":true}`); w.Code != 200 {
+		t.Errorf("forced bulk close status = %d, want 200", w.Code)
+	}
+}
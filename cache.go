@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// beadsSnapshot is one cached /api/beads fan-out.
+type beadsSnapshot struct {
+	dirs, args []string
+	beads      []json.RawMessage
+	errs       []dirError
+	fetched    time.Time
+	// lastRead is when a client last asked for this query; the background
+	// refresh skips queries nobody reads any more.
+	lastRead time.Time
+	// retain is how long the entry is kept without being read.
+	retain time.Duration
+}
+
+// maxCacheEntries caps the beads cache. Relative date filters produce a
+// new query (and key) every second, so the cap matters even with eviction.
+const maxCacheEntries = 256
+
+// beadsCache keeps recent fan-out results keyed by their bd list
+// arguments, so repeated polls within Config.BeadsCacheMs don't spawn a
+// bd per rig each time. Partial results are never cached.
+type beadsCache struct {
+	mu      sync.Mutex
+	entries map[string]beadsSnapshot
+}
+
+var beadCache = &beadsCache{entries: make(map[string]beadsSnapshot)}
+
+func cacheKey(dirs, args []string) string {
+	return strings.Join(dirs, "\x00") + "\x01" + strings.Join(args, "\x00")
+}
+
+func (c *beadsCache) get(dirs, args []string, ttl time.Duration) (beadsSnapshot, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := cacheKey(dirs, args)
+	snap, ok := c.entries[key]
+	if !ok {
+		return beadsSnapshot{}, false
+	}
+	snap.lastRead = time.Now()
+	c.entries[key] = snap
+	if time.Since(snap.fetched) >= ttl {
+		return beadsSnapshot{}, false
+	}
+	return snap, true
+}
+
+// put stores a fan-out result, kept while it is read at least every
+// retain. A query new to the cache counts as read now; refreshing an
+// existing one keeps its lastRead. Entries unread for longer than their
+// retain are dropped, and beyond maxCacheEntries the least recently read
+// ones go too.
+func (c *beadsCache) put(dirs, args []string, beads []json.RawMessage, errs []dirError, retain time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	key := cacheKey(dirs, args)
+	lastRead := now
+	if old, ok := c.entries[key]; ok {
+		lastRead = old.lastRead
+		retain = max(retain, old.retain)
+	}
+	c.entries[key] = beadsSnapshot{dirs: dirs, args: args, beads: beads, errs: errs, fetched: now, lastRead: lastRead, retain: retain}
+
+	for k, snap := range c.entries {
+		if k != key && now.Sub(snap.lastRead) >= snap.retain {
+			delete(c.entries, k)
+		}
+	}
+	for len(c.entries) > maxCacheEntries {
+		oldest := ""
+		for k, snap := range c.entries {
+			if k != key && (oldest == "" || snap.lastRead.Before(c.entries[oldest].lastRead)) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+// active returns the cached queries read within idle.
+func (c *beadsCache) active(idle time.Duration) []beadsSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []beadsSnapshot
+	for _, snap := range c.entries {
+		if time.Since(snap.lastRead) < idle {
+			out = append(out, snap)
+		}
+	}
+	return out
+}
+
+// clear drops every entry, e.g. after the prefix map changed.
+func (c *beadsCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]beadsSnapshot)
+}
+
+// fetchBeads is collectBeads behind the cache. A zero ttl bypasses it.
+func fetchBeads(ctx context.Context, dirs, args []string, ttl time.Duration) (beads []json.RawMessage, errs []dirError, partial bool) {
+	if ttl > 0 {
+		if snap, ok := beadCache.get(dirs, args, ttl); ok {
+			return snap.beads, snap.errs, false
+		}
+	}
+	beads, errs, partial = collectBeads(ctx, dirs, args)
+	if ttl > 0 && !partial {
+		beadCache.put(dirs, args, beads, errs, ttl)
+	}
+	return beads, errs, partial
+}
+
+// beadsTTL is how long a cached fan-out may be served. With the
+// background refresh on it covers two refresh intervals, so clients keep
+// reading the cache between refreshes.
+func beadsTTL(cfg Config) time.Duration {
+	ttl := time.Duration(cfg.BeadsCacheMs) * time.Millisecond
+	if ttl > 0 && cfg.BackgroundRefreshSec > 0 {
+		ttl = max(ttl, 2*time.Duration(cfg.BackgroundRefreshSec)*time.Second)
+	}
+	return ttl
+}
+
+// refreshActive re-runs every cached query read within idle and stores the
+// results. It returns how many queries it refreshed.
+func refreshActive(ctx context.Context, idle time.Duration) int {
+	n := 0
+	for _, snap := range beadCache.active(idle) {
+		ctx, cancel := withBeadsBudget(ctx)
+		beads, errs, partial := collectBeads(ctx, snap.dirs, snap.args)
+		cancel()
+		if !partial {
+			beadCache.put(snap.dirs, snap.args, beads, errs, idle)
+			n++
+		}
+	}
+	return n
+}
+
+// startBackgroundRefresh keeps the beads cache warm every
+// Config.BackgroundRefreshSec. It pauses by itself once no client has read
+// a cached query for Config.BackgroundIdleSec.
+func startBackgroundRefresh(ctx context.Context, cfg Config) {
+	if cfg.BackgroundRefreshSec <= 0 {
+		return
+	}
+	if cfg.BeadsCacheMs <= 0 {
+		log.Printf("warning: backgroundRefreshSec needs beadsCacheMs; background refresh is off")
+		return
+	}
+	idle := 5 * time.Minute
+	if cfg.BackgroundIdleSec > 0 {
+		idle = time.Duration(cfg.BackgroundIdleSec) * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.BackgroundRefreshSec) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				refreshActive(ctx, idle)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// warming is set while the startup prewarm fan-out is running.
+var warming atomic.Bool
+
+// startPrewarm fills the cache for the unfiltered bead list in the
+// background. Until it finishes /api/beads answers 503 (see handleBeads).
+func startPrewarm(ctx context.Context, cfg Config) {
+	if !cfg.PrewarmBeads || cfg.BeadsCacheMs <= 0 {
+		return
+	}
+	warming.Store(true)
+	go func() {
+		defer warming.Store(false)
+		start := time.Now()
+		ctx, cancel := withBeadsBudget(ctx)
+		defer cancel()
+		dirs, _ := limitDirs(beadDirs(), cfg.MaxRigs)
+		beads, _, partial := fetchBeads(ctx, dirs, nil, beadsTTL(cfg))
+		log.Printf("prewarm: %d beads in %s (partial=%v)", len(beads), time.Since(start).Round(time.Millisecond), partial)
+	}()
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleBeadsWarmup(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","status":"open"}]'`)
+
+	warming.Store(true)
+	defer warming.Store(false)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads", nil))
+	if w.Code != 503 {
+		t.Fatalf("during warmup status = %d, want 503", w.Code)
+	}
+	if ra := w.Header().Get("Retry-After"); ra != "5" {
+		t.Errorf("Retry-After = %q, want default 5", ra)
+	}
+
+	cfg := loadConfig()
+	cfg.WarmupServeCold = true
+	saveConfig(cfg)
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads", nil))
+	if w.Code != 200 {
+		t.Errorf("warmupServeCold status = %d, want 200", w.Code)
+	}
+
+	warming.Store(false)
+	cfg.WarmupServeCold = false
+	saveConfig(cfg)
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads", nil))
+	if w.Code != 200 {
+		t.Errorf("after warmup status = %d, want 200", w.Code)
+	}
+}
+
+func TestHandleBeadsCache(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+	beadCache.clear()
+	t.Cleanup(beadCache.clear)
+
+	cfg := loadConfig()
+	cfg.BeadsCacheMs = 60000
+	saveConfig(cfg)
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeBin(t, "bd", `echo x >> `+calls+`
+echo '[{"id":"ri-1","status":"open"}]'
+`)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handleBeads(w, httptest.NewRequest("GET", "/api/beads?status=open", nil))
+		if w.Code != 200 {
+			t.Fatalf("status = %d", w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?status=closed", nil))
+
+	data, _ := os.ReadFile(calls)
+	if n := strings.Count(string(data), "x"); n != 2 {
+		t.Errorf("bd ran %d times, want 2 (one per distinct query)", n)
+	}
+}
+
+func TestHandleBeadsMaxRigs(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	root := t.TempDir()
+	prefixMap = map[string]string{
+		"aa": filepath.Join(root, "a", ".beads"),
+		"bb": filepath.Join(root, "b", ".beads"),
+		"cc": filepath.Join(root, "c", ".beads"),
+		"dd": filepath.Join(root, "d", ".beads"),
+	}
+	cfg := loadConfig()
+	cfg.MaxRigs = 2
+	saveConfig(cfg)
+
+	fakeBin(t, "bd", `echo "[{\"id\":\"$(basename $(dirname $BEADS_DIR))-1\"}]"`)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads", nil))
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("X-Rigradar-Truncated") != "true" {
+		t.Error("truncated fan-out should set X-Rigradar-Truncated")
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"a-1"`) || !strings.Contains(body, `"b-1"`) || strings.Contains(body, `"c-1"`) || strings.Contains(body, `"d-1"`) {
+		t.Errorf("beads = %s, want only the first two rigs (a, b)", body)
+	}
+}
+
+func TestHandleBeadsExplain(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{
+		"hq":       "/town/.beads",
+		"ri":       "/town/rigradar/.beads",
+		"rigradar": "/town/rigradar/.beads",
+	}
+	fakeBin(t, "bd", `echo "explain must not run bd" >&2; exit 1`)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?explain=1", nil))
+	var got struct {
+		Dirs      []dirExplanation `json:"dirs"`
+		Truncated bool             `json:"truncated"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("explain = %s", w.Body)
+	}
+	if len(got.Dirs) != 2 || got.Truncated {
+		t.Fatalf("explain = %+v, want 2 unique dirs", got)
+	}
+	if d := got.Dirs[1]; d.Dir != "/town/rigradar/.beads" || strings.Join(d.Prefixes, ",") != "ri,rigradar" {
+		t.Errorf("rigradar dir = %+v, want prefixes ri,rigradar", d)
+	}
+}
+
+func TestHandleBeadsRigGlob(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	root := t.TempDir()
+	prefixMap = map[string]string{
+		"hq":        filepath.Join(root, "hq", ".beads"),
+		"ta":        filepath.Join(root, "team-a", ".beads"),
+		"team-a":    filepath.Join(root, "team-a", ".beads"),
+		"team-b":    filepath.Join(root, "team-b", ".beads"),
+		"ops":       filepath.Join(root, "ops", ".beads"),
+		"ops-teams": filepath.Join(root, "ops", ".beads"),
+	}
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeBin(t, "bd", `basename $(dirname $BEADS_DIR) >> `+calls+`
+echo "[{\"id\":\"$(basename $(dirname $BEADS_DIR))-1\",\"status\":\"open\"}]"`)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?rigGlob=team-*&status=open", nil))
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body: %s", w.Code, w.Body)
+	}
+	data, _ := os.ReadFile(calls)
+	queried := strings.Fields(string(data))
+	sort.Strings(queried)
+	if strings.Join(queried, ",") != "team-a,team-b" {
+		t.Errorf("queried rigs %v, want only team-a and team-b", queried)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"team-a-1"`) || !strings.Contains(body, `"team-b-1"`) || strings.Contains(body, `"ops-1"`) {
+		t.Errorf("beads = %s, want only the team-* rigs", body)
+	}
+
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?rigGlob=team-[", nil))
+	if w.Code != 400 {
+		t.Errorf("malformed glob status = %d, want 400", w.Code)
+	}
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+	beadCache.clear()
+	t.Cleanup(beadCache.clear)
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeBin(t, "bd", `echo x >> `+calls+`
+echo "[{\"id\":\"ri-$(wc -l < `+calls+` | tr -d ' ')\"}]"
+`)
+	cfg := Config{BeadsCacheMs: 1000, BackgroundRefreshSec: 30}
+	if ttl := beadsTTL(cfg); ttl != time.Minute {
+		t.Errorf("beadsTTL = %s, want two refresh intervals", ttl)
+	}
+
+	dirs := beadDirs()
+	args := []string{"--status=open"}
+	beads, _, _ := fetchBeads(context.Background(), dirs, args, beadsTTL(cfg))
+	if len(beads) != 1 || !strings.Contains(string(beads[0]), `"ri-1"`) {
+		t.Fatalf("first fetch = %s", beads)
+	}
+
+	if n := refreshActive(context.Background(), time.Minute); n != 1 {
+		t.Fatalf("refreshed %d queries, want 1", n)
+	}
+	beads, _, _ = fetchBeads(context.Background(), dirs, args, beadsTTL(cfg))
+	if !strings.Contains(string(beads[0]), `"ri-2"`) {
+		t.Errorf("after refresh = %s, want the refreshed snapshot from the cache", beads)
+	}
+
+	// Nobody has read the query within a zero idle window: nothing runs.
+	if n := refreshActive(context.Background(), 0); n != 0 {
+		t.Errorf("idle refresh ran %d queries, want 0", n)
+	}
+	data, _ := os.ReadFile(calls)
+	if n := strings.Count(string(data), "x"); n != 2 {
+		t.Errorf("bd ran %d times, want 2 (the first fetch and one refresh)", n)
+	}
+}
+
+func TestBeadsCacheEvicts(t *testing.T) {
+	beadCache.clear()
+	t.Cleanup(beadCache.clear)
+	dirs := []string{"/town/.beads"}
+	size := func() int {
+		beadCache.mu.Lock()
+		defer beadCache.mu.Unlock()
+		return len(beadCache.entries)
+	}
+
+	// An entry nobody reads within its retain window goes on the next put.
+	beadCache.put(dirs, []string{"--created-after=old"}, nil, nil, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	beadCache.put(dirs, []string{"--created-after=new"}, nil, nil, time.Minute)
+	if _, ok := beadCache.get(dirs, []string{"--created-after=old"}, time.Hour); ok || size() != 1 {
+		t.Errorf("expired entry kept: %d entries", size())
+	}
+
+	// Distinct queries (as relative dates produce) never exceed the cap;
+	// the least recently read go first.
+	for i := range maxCacheEntries + 50 {
+		beadCache.put(dirs, []string{fmt.Sprintf("--created-after=%d", i)}, nil, nil, time.Hour)
+	}
+	if n := size(); n != maxCacheEntries {
+		t.Errorf("cache holds %d entries, want the cap %d", n, maxCacheEntries)
+	}
+	if _, ok := beadCache.get(dirs, []string{fmt.Sprintf("--created-after=%d", maxCacheEntries+49)}, time.Hour); !ok {
+		t.Error("the newest entry should survive the cap")
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// maxConfigImportBytes bounds an uploaded config file.
+const maxConfigImportBytes = 1 << 20
+
+func handleExportConfig(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="rigradar-config.json"`)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(append(data, '\n'))
+}
+
+// handleImportConfig replaces the client settings in config.json with an
+// uploaded file, through the same checks as POST /api/config. Client
+// settings the file leaves out get their defaults.
+func handleImportConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxConfigImportBytes+1))
+	if err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxConfigImportBytes {
+		sendError(w, "config file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&patch); err != nil {
+		sendError(w, "invalid config: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dec.More() {
+		sendError(w, "invalid config: trailing data after the JSON object", http.StatusBadRequest)
+		return
+	}
+	if cfg, ok := applyClientConfig(w, patch, true); ok {
+		sendJSON(w, cfg, http.StatusOK)
+	}
+}
+
+// clientSettings are the top-level config keys clients may change through
+// POST /api/config and /api/config/import: display settings the UI
+// changes. Everything else, in particular anything that affects which
+// commands run or how, is only set in config.json.
+var clientSettings = map[string]bool{
+	"filters":             true,
+	"server":              true,
+	"refreshInterval":     true,
+	"theme":               true,
+	"bannerMessage":       true,
+	"bannerLevel":         true,
+	"templates":           true,
+	"defaultRig":          true,
+	"autoRefreshStatuses": true,
+}
+
+// applyClientConfig is the one path by which clients change config.json.
+// Keys outside clientSettings are rejected unless they repeat the current
+// value, so a file from /api/config/export can be imported back. With
+// replace, client settings patch leaves out go back to their defaults;
+// otherwise patch is deep-merged onto the current config. The result is
+// validated before it is saved. On failure the error has been sent and ok
+// is false.
+func applyClientConfig(w http.ResponseWriter, patch map[string]json.RawMessage, replace bool) (cfg Config, ok bool) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	base, err := json.Marshal(loadConfig())
+	if err != nil {
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return cfg, false
+	}
+	var merged map[string]json.RawMessage
+	json.Unmarshal(base, &merged)
+
+	for key, value := range patch {
+		if !clientSettings[key] && !sameJSON(value, merged[key]) {
+			sendError(w, fmt.Sprintf("%q cannot be changed through the API; edit config.json", key), http.StatusBadRequest)
+			return cfg, false
+		}
+	}
+	if replace {
+		for key := range clientSettings {
+			delete(merged, key)
+		}
+		for key, value := range patch {
+			merged[key] = value
+		}
+	} else {
+		mergeJSON(merged, patch)
+	}
+	data, _ := json.Marshal(merged)
+
+	cfg = defaultConfig()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		sendError(w, "invalid config: "+err.Error(), http.StatusBadRequest)
+		return cfg, false
+	}
+	if err := validateConfig(cfg); err != nil {
+		sendError(w, "invalid config: "+err.Error(), http.StatusBadRequest)
+		return cfg, false
+	}
+	if err := saveConfig(cfg); err != nil {
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return cfg, false
+	}
+	return cfg, true
+}
+
+// sameJSON reports whether a and b encode the same value. A missing value
+// counts as null.
+func sameJSON(a, b json.RawMessage) bool {
+	var va, vb any
+	if len(a) > 0 && json.Unmarshal(a, &va) != nil {
+		return false
+	}
+	if len(b) > 0 && json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// mergeJSON deep-merges patch into base: objects on both sides are merged
+// key by key, and any other value in patch, null included, replaces the
+// one in base. Keys patch leaves out are untouched.
+func mergeJSON(base, patch map[string]json.RawMessage) {
+	for key, value := range patch {
+		var sub, baseSub map[string]json.RawMessage
+		if json.Unmarshal(value, &sub) == nil && sub != nil && json.Unmarshal(base[key], &baseSub) == nil && baseSub != nil {
+			mergeJSON(baseSub, sub)
+			base[key], _ = json.Marshal(baseSub)
+			continue
+		}
+		base[key] = value
+	}
+}
+
+// validateConfig rejects values rigradar cannot run with.
+func validateConfig(cfg Config) error {
+	if cfg.Server.Port < 0 || cfg.Server.Port > 65535 {
+		return fmt.Errorf("server.port %d out of range", cfg.Server.Port)
+	}
+	for name, v := range map[string]int{
+		"refreshInterval":         cfg.RefreshInterval,
+		"maxConcurrentCommands":   cfg.MaxConcurrentCommands,
+		"beadsBudgetMs":           cfg.BeadsBudgetMs,
+		"maxRigs":                 cfg.MaxRigs,
+		"beadsCacheMs":            cfg.BeadsCacheMs,
+		"readTimeoutSec":          cfg.ReadTimeoutSec,
+		"writeTimeoutSec":         cfg.WriteTimeoutSec,
+		"idleTimeoutMinutes":      cfg.IdleTimeoutMinutes,
+		"backgroundRefreshSec":    cfg.BackgroundRefreshSec,
+		"backgroundIdleSec":       cfg.BackgroundIdleSec,
+		"historyMaxEntries":       cfg.HistoryMaxEntries,
+		"historyRetentionMinutes": cfg.HistoryRetentionMinutes,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if err := oneOf("bannerLevel", cfg.BannerLevel, "info", "warn"); err != nil {
+		return err
+	}
+	if err := oneOf("theme", cfg.Theme, "dark", "light", "auto"); err != nil {
+		return err
+	}
+	if err := oneOf("watchMode", cfg.WatchMode, "poll", "off"); err != nil {
+		return err
+	}
+	if err := oneOf("logFormat", cfg.LogFormat, "text", "json"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// oneOf accepts an empty value (the default) or one of allowed.
+func oneOf(name, value string, allowed ...string) error {
+	if value == "" || slices.Contains(allowed, value) {
+		return nil
+	}
+	return fmt.Errorf("%s %q is not one of %q", name, value, allowed)
+}
+
+// flagOverrides are the settings given as command-line flags, keyed like
+// configSources. main fills it where flags are applied over config.json.
+var flagOverrides = map[string]any{}
+
+// configSources reports where each effective setting comes from: "flag",
+// "file" (present in config.json) or "default". Keys are JSON field names,
+// with server and filters settings as "server.port" etc.
+func configSources(fileData []byte) map[string]string {
+	var file map[string]json.RawMessage
+	json.Unmarshal(fileData, &file)
+
+	sources := make(map[string]string)
+	t := reflect.TypeFor[Config]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name := jsonName(f)
+		if f.Type.Kind() != reflect.Struct {
+			sources[name] = sourceOf(file, name)
+			continue
+		}
+		var inner map[string]json.RawMessage
+		json.Unmarshal(file[name], &inner)
+		for j := range f.Type.NumField() {
+			sub := jsonName(f.Type.Field(j))
+			sources[name+"."+sub] = sourceOf(inner, sub)
+		}
+	}
+	for key := range flagOverrides {
+		sources[key] = "flag"
+	}
+	return sources
+}
+
+// handleConfigSchema describes config.json: the JSON type of each setting,
+// keyed like configSources, the keys clients may change, and which
+// mutations the current config enables.
+func handleConfigSchema(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+
+	fields := make(map[string]string)
+	t := reflect.TypeFor[Config]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name := jsonName(f)
+		if f.Type.Kind() != reflect.Struct {
+			fields[name] = jsonType(f.Type)
+			continue
+		}
+		for j := range f.Type.NumField() {
+			sub := f.Type.Field(j)
+			fields[name+"."+jsonName(sub)] = jsonType(sub.Type)
+		}
+	}
+	editable := slices.Sorted(maps.Keys(clientSettings))
+	sendJSON(w, map[string]any{
+		"fields":           fields,
+		"clientSettings":   editable,
+		"enabledMutations": enabledMutations(cfg),
+	}, http.StatusOK)
+}
+
+// jsonType names the JSON type a Go type encodes to.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+func sourceOf(file map[string]json.RawMessage, key string) string {
+	if _, ok := file[key]; ok {
+		return "file"
+	}
+	return "default"
+}
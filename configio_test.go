@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConfigExportImport(t *testing.T) {
+	origPath := configPath
+	defer func() { configPath = origPath }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	cfg := loadConfig()
+	cfg.RefreshInterval = 12345
+	cfg.Theme = "light"
+	cfg.ReadOnly = true
+	saveConfig(cfg)
+
+	w := httptest.NewRecorder()
+	handleExportConfig(w, httptest.NewRequest("GET", "/api/config/export", nil))
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="rigradar-config.json"`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	var exported Config
+	if err := json.Unmarshal(w.Body.Bytes(), &exported); err != nil || exported.RefreshInterval != 12345 {
+		t.Fatalf("export = %s", w.Body)
+	}
+
+	// An exported file imports back: its operator settings match.
+	export := w.Body.String()
+	w = httptest.NewRecorder()
+	handleImportConfig(w, httptest.NewRequest("POST", "/api/config/import", strings.NewReader(export)))
+	if w.Code != 200 {
+		t.Fatalf("re-importing the export status = %d, body: %s", w.Code, w.Body)
+	}
+
+	// A good file is saved; client settings it leaves out get defaults.
+	w = httptest.NewRecorder()
+	handleImportConfig(w, httptest.NewRequest("POST", "/api/config/import", strings.NewReader(`{"refreshInterval": 5000}`)))
+	if w.Code != 200 {
+		t.Fatalf("import status = %d, body: %s", w.Code, w.Body)
+	}
+	got := loadConfig()
+	if got.RefreshInterval != 5000 || got.Theme != "" || !got.ReadOnly {
+		t.Errorf("after import refresh=%d theme=%q readOnly=%v, want 5000, the default theme and still read-only", got.RefreshInterval, got.Theme, got.ReadOnly)
+	}
+	if got.Server.Port != 9292 {
+		t.Errorf("omitted fields should get defaults, port = %d", got.Server.Port)
+	}
+
+	// Anything but client settings is rejected, as with POST /api/config.
+	for _, body := range []string{
+		`{"refreshInterval": 5000, "readOnly": false}`,
+		`{"execWrapper": "sh -c {cmd}"}`,
+		`{"proxyEnv": {"HTTPS_PROXY": "http://evil:8080"}}`,
+		`{"directRead": true}`,
+		`{"prefixOverrides": {"ri": "/tmp"}}`,
+		`{"maxConcurrentCommands": 100}`,
+	} {
+		w = httptest.NewRecorder()
+		handleImportConfig(w, httptest.NewRequest("POST", "/api/config/import", strings.NewReader(body)))
+		if w.Code != 400 {
+			t.Errorf("import %s status = %d, want 400", body, w.Code)
+		}
+	}
+	if got := loadConfig(); !got.ReadOnly || got.ExecWrapper != "" || got.ProxyEnv != nil || got.DirectRead || got.PrefixOverrides != nil {
+		t.Errorf("rejected imports changed the config: %+v", got)
+	}
+
+	for _, bad := range []string{
+		`{"refreshInterval": `,
+		`{"refreshIntervall": 5000}`,
+		`{"server": {"port": 70000}}`,
+		`{"watchMode": "inotify"}`,
+		`{"watchMode": "native"}`,
+		`[1, 2]`,
+	} {
+		w = httptest.NewRecorder()
+		handleImportConfig(w, httptest.NewRequest("POST", "/api/config/import", strings.NewReader(bad)))
+		if w.Code != 400 {
+			t.Errorf("import %s status = %d, want 400", bad, w.Code)
+		}
+	}
+	if loadConfig().RefreshInterval != 5000 {
+		t.Error("a rejected import must not change config.json")
+	}
+}
+
+func TestConfigSources(t *testing.T) {
+	origFlags := flagOverrides
+	defer func() { flagOverrides = origFlags }()
+	flagOverrides = map[string]any{"server.port": 8080}
+
+	sources := configSources([]byte(`{"refreshInterval": 5000, "server": {"host": "127.0.0.1"}}`))
+	for key, want := range map[string]string{
+		"refreshInterval":         "file",
+		"server.host":             "file",
+		"server.port":             "flag",
+		"readOnly":                "default",
+		"filters.hideSystemBeads": "default",
+		"maxConcurrentCommands":   "default",
+	} {
+		if sources[key] != want {
+			t.Errorf("sources[%q] = %q, want %q", key, sources[key], want)
+		}
+	}
+	if _, ok := sources["server"]; ok {
+		t.Error("nested settings should be reported per field, not as server")
+	}
+}
+
+func TestPostConfigDeepMerge(t *testing.T) {
+	origPath := configPath
+	defer func() { configPath = origPath }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	cfg := loadConfig()
+	cfg.Server = ServerConfig{Port: 9292, Host: "localhost"}
+	cfg.Filters = Filters{HideSystemBeads: true, HideEvents: true}
+	cfg.RefreshInterval = 5000
+	cfg.ReadOnly = true
+	saveConfig(cfg)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handlePostConfig(w, httptest.NewRequest("POST", "/api/config", strings.NewReader(body)))
+		return w
+	}
+
+	if w := post(`{"server":{"port":5555},"filters":{"hideEvents":false}}`); w.Code != 200 {
+		t.Fatalf("partial update status = %d %s", w.Code, w.Body)
+	}
+	got := loadConfig()
+	if got.Server.Port != 5555 || got.Server.Host != "localhost" {
+		t.Errorf("server = %+v, want port 5555 with host kept", got.Server)
+	}
+	if !got.Filters.HideSystemBeads || got.Filters.HideEvents {
+		t.Errorf("filters = %+v, want only hideEvents cleared", got.Filters)
+	}
+	if got.RefreshInterval != 5000 {
+		t.Errorf("refreshInterval = %d, want it untouched", got.RefreshInterval)
+	}
+	for _, body := range []string{`{"readOnly":false}`, `{"execWrapper":"sh -c {cmd}"}`, `{"proxyEnv":{"HTTPS_PROXY":"http://evil"}}`, `{"theme":"light","directRead":true}`} {
+		if w := post(body); w.Code != 400 {
+			t.Errorf("POST %s status = %d, want 400", body, w.Code)
+		}
+	}
+	if got := loadConfig(); !got.ReadOnly || got.ExecWrapper != "" || got.ProxyEnv != nil || got.DirectRead || got.Theme != "" {
+		t.Errorf("rejected keys changed the config: %+v", got)
+	}
+
+	if w := post(`{"refreshInterval":null}`); w.Code != 200 {
+		t.Fatalf("null update status = %d %s", w.Code, w.Body)
+	}
+	if got := loadConfig(); got.RefreshInterval != defaultConfig().RefreshInterval || got.Server.Port != 5555 {
+		t.Errorf("after null: refreshInterval = %d, port = %d, want the default and 5555", got.RefreshInterval, got.Server.Port)
+	}
+
+	for _, body := range []string{`{"server":{"prot":1}}`, `{"server":{"port":-1}}`, `{"filters":"all"}`} {
+		if w := post(body); w.Code != 400 {
+			t.Errorf("POST %s status = %d, want 400", body, w.Code)
+		}
+	}
+	if got := loadConfig(); got.Server.Port != 5555 {
+		t.Errorf("rejected updates changed the config: port = %d", got.Server.Port)
+	}
+}
+
+func TestPostConfigTogglesOneFilter(t *testing.T) {
+	origPath := configPath
+	defer func() { configPath = origPath }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	cfg := loadConfig()
+	cfg.Filters = Filters{HideSystemBeads: true, HideEvents: false, HideRigIdentity: false, HideMaintenanceWisps: true, HideHQBeads: true}
+	saveConfig(cfg)
+
+	for _, on := range []bool{true, false} {
+		body := `{"filters":{"hideEvents":false}}`
+		if on {
+			body = `{"filters":{"hideEvents":true}}`
+		}
+		w := httptest.NewRecorder()
+		handlePostConfig(w, httptest.NewRequest("POST", "/api/config", strings.NewReader(body)))
+		if w.Code != 200 {
+			t.Fatalf("toggle status = %d %s", w.Code, w.Body)
+		}
+		want := Filters{HideSystemBeads: true, HideEvents: on, HideRigIdentity: false, HideMaintenanceWisps: true, HideHQBeads: true}
+		if got := loadConfig().Filters; got != want {
+			t.Errorf("after hideEvents=%v filters = %+v, want %+v", on, got, want)
+		}
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	origPath := configPath
+	defer func() { configPath = origPath }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	cfg := loadConfig()
+	cfg.EnabledMutations = []string{"close"}
+	saveConfig(cfg)
+
+	w := httptest.NewRecorder()
+	handleConfigSchema(w, httptest.NewRequest("GET", "/api/config/schema", nil))
+	var schema struct {
+		Fields           map[string]string `json:"fields"`
+		ClientSettings   []string          `json:"clientSettings"`
+		EnabledMutations map[string]bool   `json:"enabledMutations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &schema); err != nil {
+		t.Fatalf("schema = %s: %v", w.Body, err)
+	}
+	for key, want := range map[string]string{
+		"readOnly":         "boolean",
+		"refreshInterval":  "number",
+		"server.port":      "number",
+		"enabledMutations": "array",
+		"templates":        "object",
+	} {
+		if schema.Fields[key] != want {
+			t.Errorf("fields[%q] = %q, want %q", key, schema.Fields[key], want)
+		}
+	}
+	if !slices.Contains(schema.ClientSettings, "theme") || slices.Contains(schema.ClientSettings, "readOnly") {
+		t.Errorf("clientSettings = %v", schema.ClientSettings)
+	}
+	want := map[string]bool{"create": false, "update": false, "close": true, "gt": false}
+	if !maps.Equal(schema.EnabledMutations, want) {
+		t.Errorf("enabledMutations = %v, want %v", schema.EnabledMutations, want)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// beadFields are the optional fields of a new bead. Config.Templates
+// stores them by name as defaults for POST /api/bead?template=.
+type beadFields struct {
+	Type        string   `json:"type,omitempty"`
+	Priority    *int     `json:"priority,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Assignee    string   `json:"assignee,omitempty"`
+	// Rig is a prefix or rig name; empty uses Config.DefaultRig.
+	Rig string `json:"rig,omitempty"`
+}
+
+type createBeadRequest struct {
+	Title string `json:"title"`
+	beadFields
+}
+
+// withDefaults fills every field the request left empty from tpl.
+func (f beadFields) withDefaults(tpl beadFields) beadFields {
+	if f.Type == "" {
+		f.Type = tpl.Type
+	}
+	if f.Priority == nil {
+		f.Priority = tpl.Priority
+	}
+	if f.Labels == nil {
+		f.Labels = tpl.Labels
+	}
+	if f.Description == "" {
+		f.Description = tpl.Description
+	}
+	if f.Assignee == "" {
+		f.Assignee = tpl.Assignee
+	}
+	if f.Rig == "" {
+		f.Rig = tpl.Rig
+	}
+	return f
+}
+
+// createArgs builds the `bd create` arguments for a request. Field values
+// only ever appear as --flag=value, and the title comes after "--", so no
+// body value can be read as a bd flag.
+func createArgs(title string, f beadFields) []string {
+	args := []string{"create", "--json"}
+	if f.Type != "" {
+		args = append(args, "--type="+f.Type)
+	}
+	if f.Priority != nil {
+		args = append(args, "--priority="+strconv.Itoa(*f.Priority))
+	}
+	if len(f.Labels) > 0 {
+		args = append(args, "--labels="+strings.Join(f.Labels, ","))
+	}
+	if f.Description != "" {
+		args = append(args, "--description="+f.Description)
+	}
+	if f.Assignee != "" {
+		args = append(args, "--assignee="+f.Assignee)
+	}
+	return append(args, "--", title)
+}
+
+func handleCreateBead(w http.ResponseWriter, r *http.Request) {
+	var body createBeadRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if name := r.URL.Query().Get("template"); name != "" {
+		configMu.RLock()
+		tpl, ok := loadConfig().Templates[name]
+		configMu.RUnlock()
+		if !ok {
+			sendError(w, fmt.Sprintf("unknown template %q", name), http.StatusBadRequest)
+			return
+		}
+		body.beadFields = body.beadFields.withDefaults(tpl)
+	}
+
+	title := strings.TrimSpace(body.Title)
+	if title == "" {
+		sendError(w, "a title is required", http.StatusBadRequest)
+		return
+	}
+	dir, ok := rigBeadsDir(body.Rig)
+	if !ok {
+		sendError(w, fmt.Sprintf("unknown rig %q", body.Rig), http.StatusBadRequest)
+		return
+	}
+
+	// Like close, not tied to r.Context() so a disconnect can't interrupt
+	// the write.
+	data, err := execCmd("bd", createArgs(title, body.beadFields), map[string]string{"BEADS_DIR": dir})
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if id := createdID(data); id != "" {
+		w.Header().Set("Location", "/api/bead/"+url.PathEscape(id))
+	}
+	sendJSON(w, data, http.StatusCreated)
+}
+
+// createdID is the id of the bead `bd create --json` printed, as an object
+// or a one-element array; "" if there is none.
+func createdID(data json.RawMessage) string {
+	var list []json.RawMessage
+	if json.Unmarshal(data, &list) == nil && len(list) > 0 {
+		data = list[0]
+	}
+	return beadID(data)
+}
+
+func handleTemplates(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	templates := loadConfig().Templates
+	configMu.RUnlock()
+	if templates == nil {
+		templates = map[string]beadFields{}
+	}
+	sendJSON(w, templates, http.StatusOK)
+}
+
+// handleNewBeadPage serves the quick-create form. Rig suggestions, the
+// default rig and, when creation is off, the error notice are filled in
+// here so the page works without further requests.
+func handleNewBeadPage(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+
+	prefixes := make([]string, 0, len(currentPrefixMap()))
+	for p := range currentPrefixMap() {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	var opts strings.Builder
+	for _, p := range prefixes {
+		fmt.Fprintf(&opts, `<option value="%s">`, html.EscapeString(p))
+	}
+
+	page := bytes.Replace(newBeadHTML, []byte(`<datalist id="rigs"></datalist>`),
+		[]byte(`<datalist id="rigs">`+opts.String()+`</datalist>`), 1)
+	page = bytes.Replace(page, []byte(`placeholder="prefix, or town"`),
+		[]byte(`placeholder="default: `+html.EscapeString(defaultRig(cfg))+`"`), 1)
+	if !mutationEnabled(cfg, "create") {
+		page = bytes.Replace(page, []byte(`<p id="notice" class="error" hidden></p>`),
+			[]byte(`<p id="notice" class="error">Creating beads is disabled on this server.</p>`), 1)
+		page = bytes.Replace(page, []byte(`<fieldset id="fields">`), []byte(`<fieldset id="fields" disabled>`), 1)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateBeadTemplate(t *testing.T) {
+	origPath, origMap, origRoot := configPath, prefixMap, townRoot
+	defer func() { configPath, prefixMap, townRoot = origPath, origMap, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"hq": filepath.Join(townRoot, ".beads"), "ri": filepath.Join(townRoot, "rigradar", ".beads")}
+
+	three := 3
+	cfg := loadConfig()
+	cfg.Templates = map[string]beadFields{
+		"chore": {Type: "task", Priority: &three, Labels: []string{"maintenance"}, Rig: "ri"},
+	}
+	saveConfig(cfg)
+
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fakeBin(t, "bd", `echo "$@ dir=$BEADS_DIR" > `+argsFile+`
+echo '{"id":"ri-new"}'
+`)
+
+	w := httptest.NewRecorder()
+	body := `{"title":"Rotate logs","priority":1}`
+	handleCreateBead(w, httptest.NewRequest("POST", "/api/bead?template=chore", strings.NewReader(body)))
+	if w.Code != 201 {
+		t.Fatalf("create status = %d, body: %s", w.Code, w.Body)
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/bead/ri-new" {
+		t.Errorf("Location = %q, want /api/bead/ri-new", loc)
+	}
+	if id := beadID(w.Body.Bytes()); id != "ri-new" {
+		t.Errorf("body = %s, want the created bead", w.Body)
+	}
+	got, _ := os.ReadFile(argsFile)
+	for _, want := range []string{"create --json", "-- Rotate logs dir=", "--type=task", "--priority=1", "--labels=maintenance", "dir=" + prefixMap["ri"]} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("bd args %q missing %q", got, want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleCreateBead(w, httptest.NewRequest("POST", "/api/bead?template=nope", strings.NewReader(body)))
+	if w.Code != 400 {
+		t.Errorf("unknown template status = %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handleCreateBead(w, httptest.NewRequest("POST", "/api/bead", strings.NewReader(`{"type":"bug"}`)))
+	if w.Code != 400 {
+		t.Errorf("missing title status = %d, want 400", w.Code)
+	}
+}
+
+func TestPostConfigKeepsTemplates(t *testing.T) {
+	origPath := configPath
+	defer func() { configPath = origPath }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	cfg := loadConfig()
+	cfg.Templates = map[string]beadFields{"chore": {Type: "task"}}
+	saveConfig(cfg)
+
+	w := httptest.NewRecorder()
+	handlePostConfig(w, httptest.NewRequest("POST", "/api/config", strings.NewReader(`{"refreshInterval":5000}`)))
+	w = httptest.NewRecorder()
+	handlePostConfig(w, httptest.NewRequest("POST", "/api/config", strings.NewReader(`{"templates":{"bug":{"type":"bug"}}}`)))
+
+	w = httptest.NewRecorder()
+	handleTemplates(w, httptest.NewRequest("GET", "/api/templates", nil))
+	var templates map[string]beadFields
+	json.Unmarshal(w.Body.Bytes(), &templates)
+	if templates["chore"].Type != "task" || templates["bug"].Type != "bug" {
+		t.Errorf("templates after config posts = %+v, want chore and bug", templates)
+	}
+}
+
+func TestCreateBeadDefaultRig(t *testing.T) {
+	origPath, origMap, origRoot := configPath, prefixMap, townRoot
+	defer func() { configPath, prefixMap, townRoot = origPath, origMap, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"hq": filepath.Join(townRoot, ".beads"), "ri": filepath.Join(townRoot, "rigradar", ".beads")}
+
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fakeBin(t, "bd", `echo "dir=$BEADS_DIR" > `+argsFile+`
+echo '{"id":"x-1"}'
+`)
+	create := func(body string) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleCreateBead(w, httptest.NewRequest("POST", "/api/bead", strings.NewReader(body)))
+		if w.Code != 201 {
+			t.Fatalf("create status = %d, body: %s", w.Code, w.Body)
+		}
+		got, _ := os.ReadFile(argsFile)
+		return strings.TrimSpace(strings.TrimPrefix(string(got), "dir="))
+	}
+
+	if dir := create(`{"title":"a"}`); dir != prefixMap["hq"] {
+		t.Errorf("no defaultRig: dir = %q, want the town", dir)
+	}
+
+	cfg := loadConfig()
+	cfg.DefaultRig = "ri-"
+	saveConfig(cfg)
+	if dir := create(`{"title":"a"}`); dir != prefixMap["ri"] {
+		t.Errorf("defaultRig ri: dir = %q, want the rig", dir)
+	}
+	if dir := create(`{"title":"a","rig":"town"}`); dir != prefixMap["hq"] {
+		t.Errorf("explicit town: dir = %q, want the town", dir)
+	}
+
+	cfg.DefaultRig = "zz"
+	saveConfig(cfg)
+	if dir := create(`{"title":"a"}`); dir != prefixMap["hq"] {
+		t.Errorf("unknown defaultRig: dir = %q, want the town", dir)
+	}
+}
+
+func TestNewBeadPage(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"hq": "/town/.beads", "ri": "/town/rigradar/.beads"}
+
+	w := httptest.NewRecorder()
+	handleNewBeadPage(w, httptest.NewRequest("GET", "/new", nil))
+	page := w.Body.String()
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	for _, want := range []string{`<option value="hq"><option value="ri">`, `placeholder="default: hq"`, `<fieldset id="fields">`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+
+	cfg := loadConfig()
+	cfg.ReadOnly = true
+	saveConfig(cfg)
+	w = httptest.NewRecorder()
+	handleNewBeadPage(w, httptest.NewRequest("GET", "/new", nil))
+	page = w.Body.String()
+	if !strings.Contains(page, "Creating beads is disabled") || !strings.Contains(page, `<fieldset id="fields" disabled>`) {
+		t.Error("read-only page should show the notice and disable the form")
+	}
+}
+
+func TestMutationsRejectFlagInjection(t *testing.T) {
+	origPath, origMap, origRoot := configPath, prefixMap, townRoot
+	defer func() { configPath, prefixMap, townRoot = origPath, origMap, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"hq": filepath.Join(townRoot, ".beads")}
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fakeBin(t, "bd", `for a in "$@"; do echo "$a"; done > `+argsFile+`
+echo '{"id":"hq-1"}'
+`)
+
+	// Dashes in field values stay inside their --flag=value or after "--".
+	w := httptest.NewRecorder()
+	body := `{"title":"--db=/tmp/other.db","description":"-rf","assignee":"--all"}`
+	handleCreateBead(w, httptest.NewRequest("POST", "/api/bead", strings.NewReader(body)))
+	if w.Code != 201 {
+		t.Fatalf("create status = %d, body: %s", w.Code, w.Body)
+	}
+	data, _ := os.ReadFile(argsFile)
+	args := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if n := len(args); n < 2 || args[n-2] != "--" || args[n-1] != "--db=/tmp/other.db" {
+		t.Errorf("bd args %q: the title must follow --", args)
+	}
+	for _, a := range args[:len(args)-1] {
+		if a == "-rf" || a == "--all" {
+			t.Errorf("bd args %q: body value %q passed as its own argument", args, a)
+		}
+	}
+
+	// Ids are positional, so ids that look like flags are refused.
+	os.Remove(argsFile)
+	for _, tc := range []struct {
+		name string
+		h    http.HandlerFunc
+		path string
+		body string
+	}{
+		{"close", handleCloseBead, "/api/bead/x/close", `{}`},
+		{"update", handleUpdateBead, "/api/bead/x/update", `{"title":"t"}`},
+		{"bulk close", handleBulkClose, "/api/beads/close", `{"ids":["hq-1","--all"],"force":true}`},
+	} {
+		req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+		req.SetPathValue("id", "--all")
+		w := httptest.NewRecorder()
+		tc.h(w, req)
+		if w.Code != 400 {
+			t.Errorf("%s with id --all: status = %d, want 400", tc.name, w.Code)
+		}
+	}
+	if _, err := os.Stat(argsFile); err == nil {
+		t.Error("bd ran for a request with a flag-like id")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxCursors bounds how many /api/beads queries have a cursor remembered.
+// The least recently polled query is forgotten first; its next ?since=
+// gets a full response, as after a restart.
+const maxCursors = 32
+
+// beadCursors remembers, per /api/beads query, the latest cursor handed
+// out and a hash of every bead in that result, so ?since= can tell what
+// changed. Keeping one cursor per query means a tab whose results change
+// often can't push other tabs' cursors out.
+var beadCursors = &cursorStore{states: map[string]cursorState{}}
+
+type cursorState struct {
+	cursor string
+	beads  map[string]string // id -> hash of the bead's JSON
+}
+
+type cursorStore struct {
+	mu     sync.Mutex
+	states map[string]cursorState // by query
+	order  []string               // queries, least recently used first
+}
+
+// touch moves query to the most recently used end of s.order.
+func (s *cursorStore) touch(query string) {
+	for i, q := range s.order {
+		if q == query {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	s.order = append(s.order, query)
+}
+
+func (s *cursorStore) put(query string, st cursorState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[query] = st
+	s.touch(query)
+	if len(s.order) > maxCursors {
+		delete(s.states, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (s *cursorStore) get(query string) (cursorState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[query]
+	if ok {
+		s.touch(query)
+	}
+	return st, ok
+}
+
+// cursorQuery is the part of a request a cursor is tied to: every query
+// parameter but since, in a canonical order.
+func cursorQuery(q url.Values) string {
+	q = maps.Clone(q)
+	q.Del("since")
+	return q.Encode()
+}
+
+// beadsCursor fingerprints a result: the cursor is a hash of the query
+// and of every bead, so identical results always get the same cursor.
+func beadsCursor(query string, beads []json.RawMessage) (string, cursorState) {
+	st := cursorState{beads: make(map[string]string, len(beads))}
+	for _, raw := range beads {
+		sum := sha256.Sum256(raw)
+		st.beads[beadID(raw)] = base64.RawURLEncoding.EncodeToString(sum[:12])
+	}
+	ids := make([]string, 0, len(st.beads))
+	for id := range st.beads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	h := sha256.New()
+	h.Write([]byte(query))
+	for _, id := range ids {
+		h.Write([]byte("\n" + id + "=" + st.beads[id]))
+	}
+	st.cursor = base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+	return st.cursor, st
+}
+
+// changedSince returns the beads that are new or different compared to
+// prev, and the ids in prev that are gone.
+func changedSince(prev cursorState, beads []json.RawMessage, now cursorState) (changed []json.RawMessage, removed []string) {
+	changed = []json.RawMessage{}
+	for _, raw := range beads {
+		id := beadID(raw)
+		if prev.beads[id] != now.beads[id] {
+			changed = append(changed, raw)
+		}
+	}
+	for id := range prev.beads {
+		if _, ok := now.beads[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// applyCursor sets X-Rigradar-Cursor for a complete /api/beads result and
+// handles ?since=: an unchanged result is answered with 304 (ok false),
+// otherwise only the changed beads are returned, with the ids that are
+// gone in X-Rigradar-Removed. A cursor that is not the query's latest one
+// (superseded, forgotten, from before a restart, or from a different
+// query) gets the full result. Both headers are exposed to CORS callers.
+func applyCursor(w http.ResponseWriter, r *http.Request, beads []json.RawMessage) ([]json.RawMessage, bool) {
+	query := cursorQuery(r.URL.Query())
+	cursor, now := beadsCursor(query, beads)
+	prev, ok := beadCursors.get(query)
+	beadCursors.put(query, now)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Rigradar-Cursor, X-Rigradar-Removed")
+	w.Header().Set("X-Rigradar-Cursor", cursor)
+
+	since := r.URL.Query().Get("since")
+	if since == "" {
+		return beads, true
+	}
+	if since == cursor {
+		w.WriteHeader(http.StatusNotModified)
+		return nil, false
+	}
+	if !ok || prev.cursor != since {
+		return beads, true
+	}
+	changed, removed := changedSince(prev, beads, now)
+	if len(removed) > 0 {
+		w.Header().Set("X-Rigradar-Removed", strings.Join(removed, ","))
+	}
+	return changed, true
+}
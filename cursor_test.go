@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleBeadsSinceCursor(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+	data := filepath.Join(t.TempDir(), "beads.json")
+	write := func(s string) {
+		if err := os.WriteFile(data, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fakeBin(t, "bd", `cat `+data)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleBeads(w, httptest.NewRequest("GET", "/api/beads?status=open"+query, nil))
+		return w
+	}
+	ids := func(w *httptest.ResponseRecorder) []string {
+		t.Helper()
+		var beads []map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &beads); err != nil {
+			t.Fatalf("body %q: %v", w.Body, err)
+		}
+		var out []string
+		for _, b := range beads {
+			out = append(out, b["id"].(string))
+		}
+		return out
+	}
+
+	write(`[{"id":"ri-1","title":"a"},{"id":"ri-2","title":"b"},{"id":"ri-3","title":"c"}]`)
+	w := get("")
+	first := w.Header().Get("X-Rigradar-Cursor")
+	if first == "" || len(ids(w)) != 3 {
+		t.Fatalf("first poll = %s (cursor %q), want all beads and a cursor", w.Body, first)
+	}
+
+	if w := get("&since=" + first); w.Code != 304 || w.Header().Get("X-Rigradar-Cursor") != first {
+		t.Errorf("unchanged poll = %d cursor %q, want 304 with the same cursor", w.Code, w.Header().Get("X-Rigradar-Cursor"))
+	}
+
+	write(`[{"id":"ri-1","title":"a"},{"id":"ri-2","title":"b, edited"},{"id":"ri-4","title":"d"}]`)
+	w = get("&since=" + first)
+	second := w.Header().Get("X-Rigradar-Cursor")
+	if got := ids(w); w.Code != 200 || len(got) != 2 || got[0] != "ri-2" || got[1] != "ri-4" {
+		t.Errorf("changed poll = %d %v, want only ri-2 and ri-4", w.Code, got)
+	}
+	if got := w.Header().Get("X-Rigradar-Removed"); got != "ri-3" {
+		t.Errorf("X-Rigradar-Removed = %q, want ri-3", got)
+	}
+	if second == "" || second == first {
+		t.Errorf("cursor after a change = %q, want a new one", second)
+	}
+
+	if got := ids(get("&since=unknown")); len(got) != 3 {
+		t.Errorf("unknown cursor = %v, want the full result", got)
+	}
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?status=closed&since="+second, nil))
+	if got := ids(w); len(got) != 3 {
+		t.Errorf("cursor from another query = %v, want the full result", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "X-Rigradar-Cursor") || !strings.Contains(got, "X-Rigradar-Removed") {
+		t.Errorf("Access-Control-Expose-Headers = %q, want both cursor headers", got)
+	}
+
+	// A request rejected by validation must not replace the query's cursor.
+	third := get("").Header().Get("X-Rigradar-Cursor")
+	if w := get("&view=bogus&since=" + third); w.Code != 400 || w.Header().Get("X-Rigradar-Cursor") != "" {
+		t.Errorf("invalid view = %d cursor %q, want 400 without a cursor", w.Code, w.Header().Get("X-Rigradar-Cursor"))
+	}
+	if w := get("&groupBy=color"); w.Code != 400 {
+		t.Errorf("invalid groupBy = %d, want 400", w.Code)
+	}
+	if w := get("&since=" + third); w.Code != 304 {
+		t.Errorf("poll after rejected requests = %d, want 304", w.Code)
+	}
+}
+
+func TestCursorStoreKeepsOneCursorPerQuery(t *testing.T) {
+	s := &cursorStore{states: map[string]cursorState{}}
+	s.put("status=open", cursorState{cursor: "a"})
+	// A query whose result keeps changing takes a single slot.
+	for i := 0; i < 2*maxCursors; i++ {
+		s.put("status=closed", cursorState{cursor: fmt.Sprint(i)})
+	}
+	if st, ok := s.get("status=open"); !ok || st.cursor != "a" {
+		t.Errorf("open cursor = %+v %v, want it kept", st, ok)
+	}
+
+	for i := 0; i < maxCursors-1; i++ {
+		s.put(fmt.Sprintf("q=%d", i), cursorState{cursor: "x"})
+	}
+	// status=open was read more recently than status=closed, so the
+	// closed query is the one evicted.
+	if _, ok := s.get("status=closed"); ok {
+		t.Error("least recently used query should be evicted")
+	}
+	if _, ok := s.get("status=open"); !ok {
+		t.Error("recently used query should be kept")
+	}
+}
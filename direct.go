@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// directRead is Config.DirectRead, set by main. When on, bead listings and
+// detail read each rig's beads.db through the sqlite3 CLI in read-only
+// mode instead of running bd. Anything the direct path cannot answer
+// falls back to bd, which stays the only writer.
+var directRead bool
+
+// directColumns are the columns the direct queries rely on. A beads.db
+// without them is treated as an unknown schema.
+var directColumns = []string{
+	"issues.id", "issues.title", "issues.description", "issues.status", "issues.priority",
+	"issues.issue_type", "issues.assignee", "issues.created_at", "issues.updated_at", "issues.closed_at",
+	"labels.issue_id", "labels.label",
+	"dependencies.issue_id", "dependencies.depends_on_id", "dependencies.type",
+}
+
+// directSchema caches, per beads.db, whether its schema was recognised.
+// reloadPrefixMap and SIGHUP clear it, so a bd upgrade or migration is
+// picked up.
+var directSchema sync.Map
+
+// sqlString quotes s as an SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqliteQuery runs a read-only query against db and returns its rows.
+func sqliteQuery(ctx context.Context, db, query string) ([]map[string]any, error) {
+	data, err := execCmdContext(ctx, "sqlite3", []string{"-readonly", "-json", db, query}, nil)
+	if err != nil {
+		return nil, err
+	}
+	rows := []map[string]any{}
+	var s string
+	if json.Unmarshal(data, &s) == nil && s == "" {
+		// No rows: sqlite3 prints nothing.
+		return rows, nil
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("unexpected sqlite3 output: %s", truncateOutput(data))
+	}
+	return rows, nil
+}
+
+// directDB returns dir's beads.db after checking that its schema has the
+// columns the direct queries use. The verdict is cached in directSchema.
+func directDB(ctx context.Context, dir string) (string, error) {
+	db := filepath.Join(dir, "beads.db")
+	if ok, seen := directSchema.Load(db); seen {
+		if !ok.(bool) {
+			return "", fmt.Errorf("%s: unrecognized schema", db)
+		}
+		return db, nil
+	}
+	rows, err := sqliteQuery(ctx, db, "SELECT 'issues.' || name AS name FROM pragma_table_info('issues')"+
+		" UNION ALL SELECT 'labels.' || name FROM pragma_table_info('labels')"+
+		" UNION ALL SELECT 'dependencies.' || name FROM pragma_table_info('dependencies')")
+	if err != nil {
+		return "", err
+	}
+	have := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		name, _ := row["name"].(string)
+		have[name] = true
+	}
+	for _, col := range directColumns {
+		if !have[col] {
+			directSchema.Store(db, false)
+			log.Printf("direct read: %s has no %s column; using bd for it", db, col)
+			return "", fmt.Errorf("%s: unrecognized schema", db)
+		}
+	}
+	directSchema.Store(db, true)
+	return db, nil
+}
+
+// directSelect is the column list shared by list and show, with labels
+// gathered into a JSON array.
+const directSelect = `SELECT id, title, description, status, priority, issue_type, assignee,
+ created_at, updated_at, closed_at,
+ (SELECT json_group_array(label) FROM labels WHERE labels.issue_id = issues.id) AS labels
+ FROM issues`
+
+// directShowSelect adds the bead's dependencies to directSelect, shaped
+// like bd's: depends_on_id and type.
+const directShowSelect = `SELECT *,
+ (SELECT json_group_array(json_object('depends_on_id', depends_on_id, 'type', type))
+  FROM dependencies WHERE dependencies.issue_id = shown.id) AS dependencies
+ FROM (` + directSelect + `) AS shown`
+
+// directList answers `bd list` args from beads.db. Only --status, --type
+// and --assignee are understood; other args return an error so the
+// caller falls back to bd.
+func directList(ctx context.Context, dir string, args []string) ([]json.RawMessage, error) {
+	where := []string{"status != 'tombstone'"}
+	for _, arg := range args {
+		flag, value, _ := strings.Cut(arg, "=")
+		switch flag {
+		case "--status":
+			where = append(where, "status = "+sqlString(value))
+		case "--type":
+			where = append(where, "issue_type = "+sqlString(value))
+		case "--assignee":
+			where = append(where, "assignee = "+sqlString(value))
+		default:
+			return nil, fmt.Errorf("direct read does not support %s", flag)
+		}
+	}
+	db, err := directDB(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sqliteQuery(ctx, db, directSelect+" WHERE "+strings.Join(where, " AND ")+" ORDER BY priority, created_at")
+	if err != nil {
+		return nil, err
+	}
+	return directBeads(rows), nil
+}
+
+// directShow is `bd show id --json` from beads.db: a one-element array, or
+// an error when the bead is not there. Unlike listings it also carries the
+// bead's dependencies, which the detail view and blocker checks need.
+func directShow(ctx context.Context, dir, id string) (json.RawMessage, error) {
+	db, err := directDB(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sqliteQuery(ctx, db, directShowSelect+" WHERE id = "+sqlString(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s not found in %s", id, db)
+	}
+	data, err := json.Marshal(directBeads(rows))
+	return json.RawMessage(data), err
+}
+
+// directBeads turns rows into bd-shaped beads: the labels and dependencies
+// columns arrive as JSON text and null columns are left out, as bd does.
+func directBeads(rows []map[string]any) []json.RawMessage {
+	beads := make([]json.RawMessage, 0, len(rows))
+	for _, row := range rows {
+		if s, ok := row["labels"].(string); ok {
+			var labels []string
+			json.Unmarshal([]byte(s), &labels)
+			row["labels"] = labels
+			if len(labels) == 0 {
+				delete(row, "labels")
+			}
+		}
+		if s, ok := row["dependencies"].(string); ok {
+			var deps []map[string]any
+			json.Unmarshal([]byte(s), &deps)
+			row["dependencies"] = deps
+			if len(deps) == 0 {
+				delete(row, "dependencies")
+			}
+		}
+		for k, v := range row {
+			if v == nil {
+				delete(row, k)
+			}
+		}
+		data, _ := json.Marshal(row)
+		beads = append(beads, data)
+	}
+	return beads
+}
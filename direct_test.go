@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newBeadsDB creates a beads.db with schema using the real sqlite3 CLI.
+func newBeadsDB(t *testing.T, schema string) string {
+	t.Helper()
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	dir := filepath.Join(t.TempDir(), ".beads")
+	os.MkdirAll(dir, 0o755)
+	if out, err := exec.Command("sqlite3", filepath.Join(dir, "beads.db"), schema).CombinedOutput(); err != nil {
+		t.Fatalf("creating beads.db: %v: %s", err, out)
+	}
+	return dir
+}
+
+func TestDirectRead(t *testing.T) {
+	dir := newBeadsDB(t, `
+CREATE TABLE issues (id TEXT PRIMARY KEY, title TEXT, description TEXT, status TEXT, priority INTEGER,
+ issue_type TEXT, assignee TEXT, created_at TEXT, updated_at TEXT, closed_at TEXT);
+CREATE TABLE labels (issue_id TEXT, label TEXT);
+CREATE TABLE dependencies (issue_id TEXT, depends_on_id TEXT, type TEXT);
+INSERT INTO issues VALUES ('ri-1', 'It''s broken', 'desc', 'open', 1, 'bug', NULL, '2026-01-01T00:00:00Z', '2026-01-02T00:00:00Z', NULL);
+INSERT INTO issues VALUES ('ri-2', 'Done', '', 'closed', 2, 'task', 'nux', '2026-01-01T00:00:00Z', '2026-01-03T00:00:00Z', '2026-01-03T00:00:00Z');
+INSERT INTO labels VALUES ('ri-1', 'ui'), ('ri-1', 'urgent');
+INSERT INTO dependencies VALUES ('ri-2', 'ri-1', 'blocks');
+`)
+	orig := directRead
+	defer func() { directRead = orig }()
+	directRead = true
+	fakeBin(t, "bd", "echo 'bd should not run' >&2; exit 1")
+
+	beads, err := bdList(context.Background(), dir, []string{"--status=open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(beads) != 1 {
+		t.Fatalf("got %d beads, want 1", len(beads))
+	}
+	var bead map[string]any
+	json.Unmarshal(beads[0], &bead)
+	if bead["id"] != "ri-1" || bead["title"] != "It's broken" || bead["priority"] != 1.0 {
+		t.Errorf("bead = %v", bead)
+	}
+	if labels, _ := bead["labels"].([]any); len(labels) != 2 {
+		t.Errorf("labels = %v, want ui and urgent", bead["labels"])
+	}
+	if _, ok := bead["assignee"]; ok {
+		t.Error("null columns should be left out")
+	}
+
+	data, err := directShow(context.Background(), dir, "ri-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shown []map[string]any
+	json.Unmarshal(data, &shown)
+	if len(shown) != 1 || shown[0]["assignee"] != "nux" {
+		t.Errorf("show = %s", data)
+	}
+	if ids := blockerIDs(shown[0]); len(ids) != 1 || ids[0] != "ri-1" {
+		t.Errorf("show dependencies = %v, want ri-1 blocking", shown[0]["dependencies"])
+	}
+	if _, err := directShow(context.Background(), dir, "ri-9"); err == nil {
+		t.Error("missing bead should be an error")
+	}
+	// Quotes in values cannot break out of the literal.
+	if beads, err := directList(context.Background(), dir, []string{"--assignee=x' OR '1'='1"}); err != nil || len(beads) != 0 {
+		t.Errorf("quoted assignee: %d beads, err %v", len(beads), err)
+	}
+}
+
+func TestDirectReadFallsBackToBd(t *testing.T) {
+	dir := newBeadsDB(t, `CREATE TABLE issues (id TEXT, summary TEXT);`)
+	orig := directRead
+	defer func() { directRead = orig }()
+	directRead = true
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","title":"from bd"}]'`)
+
+	beads, err := bdList(context.Background(), dir, nil)
+	if err != nil || len(beads) != 1 {
+		t.Fatalf("unrecognized schema: %d beads, err %v", len(beads), err)
+	}
+	var bead map[string]any
+	json.Unmarshal(beads[0], &bead)
+	if bead["title"] != "from bd" {
+		t.Errorf("bead = %v, want bd's answer", bead)
+	}
+
+	// Args the direct path does not understand go to bd too.
+	if _, err := directList(context.Background(), dir, []string{"--created-after=2026-01-01"}); err == nil {
+		t.Error("unsupported arg should be an error")
+	}
+}
+
+func TestDirectSchemaRecheckedAfterReload(t *testing.T) {
+	dir := newBeadsDB(t, `CREATE TABLE issues (id TEXT, summary TEXT);`)
+	origRoot, origMap := townRoot, prefixMap
+	defer func() { townRoot, prefixMap = origRoot, origMap }()
+	townRoot = t.TempDir()
+
+	if _, err := directDB(context.Background(), dir); err == nil {
+		t.Fatal("old schema should not be recognized")
+	}
+	// bd migrates the database while rigradar runs.
+	migrate := `DROP TABLE issues;
+CREATE TABLE issues (id TEXT PRIMARY KEY, title TEXT, description TEXT, status TEXT, priority INTEGER,
+ issue_type TEXT, assignee TEXT, created_at TEXT, updated_at TEXT, closed_at TEXT);
+CREATE TABLE labels (issue_id TEXT, label TEXT);
+CREATE TABLE dependencies (issue_id TEXT, depends_on_id TEXT, type TEXT);`
+	if out, err := exec.Command("sqlite3", filepath.Join(dir, "beads.db"), migrate).CombinedOutput(); err != nil {
+		t.Fatalf("migrating beads.db: %v: %s", err, out)
+	}
+	if _, err := directDB(context.Background(), dir); err == nil {
+		t.Fatal("the verdict should be cached until a reload")
+	}
+	reloadPrefixMap()
+	if _, err := directDB(context.Background(), dir); err != nil {
+		t.Errorf("after reload: %v, want the migrated schema recognized", err)
+	}
+}
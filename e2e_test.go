@@ -6,23 +6,17 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 )
 
-// newTestServer creates a full rigradar server with all routes for E2E testing.
+// newTestServer creates a full rigradar server with all routes, under both
+// /api/ and /api/v1/, for E2E testing.
 func newTestServer() *httptest.Server {
-	mux := http.NewServeMux()
-	mux.HandleFunc("GET /", handleIndex)
-	mux.HandleFunc("GET /health", handleHealth)
-	mux.HandleFunc("GET /api/ready", handleReady)
-	mux.HandleFunc("GET /api/status", handleStatus)
-	mux.HandleFunc("GET /api/beads", handleBeads)
-	mux.HandleFunc("GET /api/bead/", handleBeadDetail)
-	mux.HandleFunc("GET /api/config", handleGetConfig)
-	mux.HandleFunc("POST /api/config", handlePostConfig)
-	return httptest.NewServer(corsMiddleware(mux))
+	return httptest.NewServer(buildHandler())
 }
 
 func get(t *testing.T, url string) (*http.Response, []byte) {
@@ -309,6 +303,51 @@ func TestE2E_ConfigPostPartialUpdate(t *testing.T) {
 	}
 }
 
+func TestE2E_APIv1(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = t.TempDir() + "/test-config.json"
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","title":"Pinned","status":"open"}]'`)
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, body := get(t, ts.URL+"/api/v1/beads?status=open")
+	var beads []map[string]any
+	json.Unmarshal(body, &beads)
+	if resp.StatusCode != 200 || len(beads) != 1 || beads[0]["id"] != "ri-1" {
+		t.Errorf("GET /api/v1/beads = %d %s, want the same beads as /api/beads", resp.StatusCode, body)
+	}
+
+	resp, body = postJSON(t, ts.URL+"/api/v1/config", `{"refreshInterval":7000}`)
+	if resp.StatusCode != 200 {
+		t.Fatalf("POST /api/v1/config = %d %s", resp.StatusCode, body)
+	}
+	resp, body = get(t, ts.URL+"/api/v1/config")
+	var cfg Config
+	json.Unmarshal(body, &cfg)
+	if resp.StatusCode != 200 || cfg.RefreshInterval != 7000 {
+		t.Errorf("GET /api/v1/config = %d refreshInterval %d, want 7000", resp.StatusCode, cfg.RefreshInterval)
+	}
+
+	req, _ := http.NewRequest("OPTIONS", ts.URL+"/api/v1/config", nil)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	pre, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre.Body.Close()
+	if got := pre.Header.Get("Access-Control-Allow-Methods"); got != "GET,POST,OPTIONS" {
+		t.Errorf("preflight on /api/v1/config allows %q, want GET,POST,OPTIONS", got)
+	}
+
+	if resp, _ := get(t, ts.URL+"/api/v1/nope"); resp.StatusCode != 404 {
+		t.Errorf("unknown v1 path = %d, want 404", resp.StatusCode)
+	}
+}
+
 // --- E2E: CORS on all endpoints ---
 
 func TestE2E_CORSPreflight(t *testing.T) {
@@ -937,6 +976,30 @@ func TestE2E_FrontendEscapeFunction(t *testing.T) {
 	}
 }
 
+func TestE2E_FrontendAutoRefreshStatuses(t *testing.T) {
+	origPath := configPath
+	defer func() { configPath = origPath }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	cfg := loadConfig()
+	cfg.AutoRefreshStatuses = []string{"open", "in_progress"}
+	saveConfig(cfg)
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	_, body := get(t, ts.URL+"/api/config")
+	if !strings.Contains(string(body), `"autoRefreshStatuses":["open","in_progress"]`) {
+		t.Errorf("config should expose autoRefreshStatuses: %s", body)
+	}
+	_, body = get(t, ts.URL+"/")
+	// The timer refresh passes auto=true; the button refreshes everything.
+	for _, want := range []string{"state.config.autoRefreshStatuses", "refreshAll(true)", `onclick="refreshAll()"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("index missing %q", want)
+		}
+	}
+}
+
 func TestE2E_FrontendDetailCommands(t *testing.T) {
 	ts := newTestServer()
 	defer ts.Close()
@@ -960,3 +1023,238 @@ func truncate(s string, n int) string {
 	}
 	return s[:n] + "..."
 }
+
+// --- E2E: Overview ---
+
+func TestE2E_OverviewPartialFailure(t *testing.T) {
+	origRoot, origMap := townRoot, prefixMap
+	defer func() { townRoot, prefixMap = origRoot, origMap }()
+
+	townRoot = t.TempDir()
+	os.MkdirAll(filepath.Join(townRoot, ".beads"), 0755)
+	os.WriteFile(filepath.Join(townRoot, ".beads", "routes.jsonl"),
+		[]byte(`{"prefix":"hq-","path":"."}`+"\n"+`{"prefix":"mr-","path":"myrig"}`+"\n"), 0644)
+	os.MkdirAll(filepath.Join(townRoot, "myrig", ".beads"), 0755)
+	os.WriteFile(filepath.Join(townRoot, "myrig", ".beads", "beads.db"), nil, 0644)
+	prefixMap = buildPrefixMap()
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, body := get(t, ts.URL+"/api/overview")
+	if resp.StatusCode != 200 {
+		t.Fatalf("overview status = %d, body: %s", resp.StatusCode, body)
+	}
+
+	var rigs []rigOverview
+	if err := json.Unmarshal(body, &rigs); err != nil {
+		t.Fatalf("overview not valid JSON: %v", err)
+	}
+	if len(rigs) != 2 {
+		t.Fatalf("overview has %d rigs, want 2: %s", len(rigs), body)
+	}
+	byName := map[string]rigOverview{}
+	for _, r := range rigs {
+		byName[r.Name] = r
+	}
+	myrig, ok := byName["myrig"]
+	if !ok {
+		t.Fatalf("overview missing myrig: %s", body)
+	}
+	if !myrig.DBExists {
+		t.Error("myrig should report dbExists")
+	}
+	if len(myrig.Prefixes) != 1 || myrig.Prefixes[0] != "mr" {
+		t.Errorf("myrig prefixes = %v, want [mr]", myrig.Prefixes)
+	}
+	if _, ok := byName["town"]; !ok {
+		t.Error("overview missing town entry")
+	}
+}
+
+func TestE2E_RigsLastModified(t *testing.T) {
+	origRoot, origMap := townRoot, prefixMap
+	defer func() { townRoot, prefixMap = origRoot, origMap }()
+
+	townRoot = t.TempDir()
+	os.MkdirAll(filepath.Join(townRoot, ".beads"), 0755)
+	os.WriteFile(filepath.Join(townRoot, ".beads", "routes.jsonl"),
+		[]byte(`{"prefix":"hq-","path":"."}`+"\n"+`{"prefix":"mr-","path":"myrig"}`+"\n"), 0644)
+	db := filepath.Join(townRoot, "myrig", ".beads", "beads.db")
+	os.MkdirAll(filepath.Dir(db), 0755)
+	os.WriteFile(db, nil, 0644)
+	mtime := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(db, mtime, mtime)
+	prefixMap = buildPrefixMap()
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, body := get(t, ts.URL+"/api/rigs")
+	if resp.StatusCode != 200 {
+		t.Fatalf("rigs status = %d, body: %s", resp.StatusCode, body)
+	}
+	var rigs []map[string]any
+	json.Unmarshal(body, &rigs)
+	byName := map[string]map[string]any{}
+	for _, r := range rigs {
+		byName[r["name"].(string)] = r
+	}
+	if got := byName["myrig"]["lastModified"]; got != "2026-05-01T12:00:00Z" {
+		t.Errorf("myrig lastModified = %v, want 2026-05-01T12:00:00Z", got)
+	}
+	town, ok := byName["town"]
+	if !ok {
+		t.Fatalf("rigs missing town entry: %s", body)
+	}
+	if v, present := town["lastModified"]; !present || v != nil {
+		t.Errorf("town without beads.db lastModified = %v (present=%v), want null", v, present)
+	}
+}
+
+func TestE2E_BeadsVerboseEnvelope(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"zz": filepath.Join(t.TempDir(), ".beads")}
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, body := get(t, ts.URL+"/api/beads?verbose=1")
+	if resp.StatusCode != 200 {
+		t.Fatalf("verbose beads status = %d", resp.StatusCode)
+	}
+
+	var env struct {
+		Beads  []json.RawMessage `json:"beads"`
+		Errors []dirError        `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &env); err != nil {
+		t.Fatalf("verbose beads not an envelope: %v\nbody: %s", err, body)
+	}
+	if env.Beads == nil || env.Errors == nil {
+		t.Errorf("verbose envelope should carry beads and errors arrays: %s", body)
+	}
+}
+
+// --- E2E: Static assets ---
+
+func TestE2E_StaticAssets(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, body := get(t, ts.URL+"/static/favicon.svg")
+	if resp.StatusCode != 200 {
+		t.Fatalf("favicon status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.Contains(ct, "image/svg+xml") {
+		t.Errorf("favicon content-type = %q, want image/svg+xml", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); !strings.Contains(cc, "max-age") {
+		t.Errorf("favicon Cache-Control = %q, want a max-age", cc)
+	}
+	if !strings.Contains(string(body), "<svg") {
+		t.Error("favicon body should be the embedded SVG")
+	}
+
+	resp, _ = get(t, ts.URL+"/static/missing.js")
+	if resp.StatusCode != 404 {
+		t.Errorf("missing asset status = %d, want 404", resp.StatusCode)
+	}
+}
+
+// --- E2E: Request ids ---
+
+func TestE2E_RequestID(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, _ := get(t, ts.URL+"/health")
+	if id := resp.Header.Get("X-Request-Id"); len(id) != 16 {
+		t.Errorf("generated X-Request-Id = %q, want 16 hex chars", id)
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL+"/api/beads?groupBy=bogus", nil)
+	req.Header.Set("X-Request-Id", "support-ticket-42")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if id := resp.Header.Get("X-Request-Id"); id != "support-ticket-42" {
+		t.Errorf("echoed X-Request-Id = %q, want support-ticket-42", id)
+	}
+	var result map[string]string
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result["requestId"] != "support-ticket-42" {
+		t.Errorf("error body requestId = %q, want support-ticket-42", result["requestId"])
+	}
+}
+
+// --- E2E: Unmatched routes ---
+
+func TestE2E_NotFound(t *testing.T) {
+	origPath := configPath
+	defer func() { configPath = origPath }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp, body := get(t, ts.URL+"/api/bogus")
+	if resp.StatusCode != 404 {
+		t.Fatalf("/api/bogus status = %d, want 404", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("/api/bogus content-type = %q, want application/json", ct)
+	}
+	if cors := resp.Header.Get("Access-Control-Allow-Origin"); cors != "*" {
+		t.Errorf("/api/bogus CORS = %q, want *", cors)
+	}
+	var result map[string]string
+	json.Unmarshal(body, &result)
+	if result["error"] != "not found" || result["code"] != "NOT_FOUND" {
+		t.Errorf("/api/bogus body = %s", body)
+	}
+
+	resp, body = get(t, ts.URL+"/rigs/gastown")
+	if resp.StatusCode != 200 || !strings.Contains(string(body), "<html") {
+		t.Errorf("client-side route should serve the UI, got %d", resp.StatusCode)
+	}
+
+	os.WriteFile(configPath, []byte(`{"plainNotFound": true}`), 0644)
+	resp, _ = get(t, ts.URL+"/rigs/gastown")
+	if resp.StatusCode != 404 {
+		t.Errorf("plainNotFound status = %d, want 404", resp.StatusCode)
+	}
+}
+
+// --- E2E: h2c ---
+
+func TestE2E_H2C(t *testing.T) {
+	ts := httptest.NewUnstartedServer(buildHandler())
+	ts.Config.Protocols = serverProtocols(Config{H2C: true})
+	ts.Start()
+	defer ts.Close()
+
+	protos := new(http.Protocols)
+	protos.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protos}, Timeout: 10 * time.Second}
+	resp, err := client.Get(ts.URL + "/livez")
+	if err != nil {
+		t.Fatalf("h2c request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("proto = %s, want HTTP/2", resp.Proto)
+	}
+	if resp.Header.Get("X-Request-Id") == "" || resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("middleware headers missing under h2c")
+	}
+
+	// Plain HTTP/1.1 clients still work.
+	resp, _ = get(t, ts.URL+"/livez")
+	if resp.ProtoMajor != 1 || resp.StatusCode != 200 {
+		t.Errorf("HTTP/1.1 fallback = %s %d", resp.Proto, resp.StatusCode)
+	}
+}
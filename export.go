@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// exportFlushEvery is how many beads a streamed export writes between
+// flushes.
+const exportFlushEvery = 500
+
+// handleExportBeads downloads every bead (optionally ?status=) as one JSON
+// array. By default the array is built in memory and sorted by id. With
+// ?stream=1 each rig's beads are written as soon as that rig answers, so
+// memory stays flat for very large towns, but the order follows whichever
+// rig answers first. Failing rigs are logged and left out; a cut-short
+// streamed export is flagged in the X-Rigradar-Partial trailer.
+func handleExportBeads(w http.ResponseWriter, r *http.Request) {
+	var args []string
+	if status := r.URL.Query().Get("status"); status != "" {
+		args = append(args, "--status="+status)
+	}
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	dirs, _ := limitDirs(beadDirs(), cfg.MaxRigs)
+	ctx, cancel := withBeadsBudget(r.Context())
+	defer cancel()
+
+	w.Header().Set("Content-Disposition", `attachment; filename="rigradar-beads.json"`)
+	if r.URL.Query().Get("stream") != "1" {
+		beads, errs, partial := collectBeads(ctx, dirs, args)
+		for _, e := range errs {
+			log.Printf("export: %s: %s", e.Dir, e.Error)
+		}
+		sort.SliceStable(beads, func(i, j int) bool { return beadID(beads[i]) < beadID(beads[j]) })
+		if partial {
+			w.Header().Set("X-Rigradar-Partial", "true")
+		}
+		sendJSON(w, beads, http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Trailer", "X-Rigradar-Partial")
+	rc := http.NewResponseController(w)
+	w.Write([]byte("["))
+	written := 0
+	outstanding := fanOutBeads(ctx, dirs, args, func(res dirBeads) {
+		if res.err != nil {
+			log.Printf("export: %s: %v", res.dir, res.err)
+			return
+		}
+		for _, bead := range res.beads {
+			if written > 0 {
+				w.Write([]byte(",\n"))
+			}
+			w.Write(bead)
+			written++
+			if written%exportFlushEvery == 0 {
+				rc.Flush()
+			}
+		}
+	})
+	w.Write([]byte("]\n"))
+	if outstanding > 0 {
+		w.Header().Set("X-Rigradar-Partial", "true")
+	}
+}
+
+func beadID(raw json.RawMessage) string {
+	var b struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(raw, &b)
+	return b.ID
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportBeads(t *testing.T) {
+	origPath, origMap, origRoot := configPath, prefixMap, townRoot
+	defer func() { configPath, prefixMap, townRoot = origPath, origMap, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{
+		"hq": filepath.Join(townRoot, ".beads"),
+		"ri": filepath.Join(townRoot, "rigradar", ".beads"),
+		"zz": filepath.Join(townRoot, "empty", ".beads"),
+	}
+	fakeBin(t, "bd", `case "$BEADS_DIR" in
+*rigradar*) echo '[{"id":"ri-2","title":"b \"quoted\""},{"id":"ri-1"}]' ;;
+*empty*) echo '[]' ;;
+*) echo '[{"id":"hq-1"}]' ;;
+esac
+`)
+
+	for _, query := range []string{"", "?stream=1"} {
+		w := httptest.NewRecorder()
+		handleExportBeads(w, httptest.NewRequest("GET", "/api/beads/export.json"+query, nil))
+		var beads []map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &beads); err != nil {
+			t.Fatalf("%q: export is not valid JSON: %v\n%s", query, err, w.Body)
+		}
+		if len(beads) != 3 {
+			t.Errorf("%q: got %d beads, want 3", query, len(beads))
+		}
+		if query == "" && (beads[0]["id"] != "hq-1" || beads[1]["id"] != "ri-1" || beads[2]["id"] != "ri-2") {
+			t.Errorf("buffered export should be sorted by id: %v", beads)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	Description string `xml:"description,omitempty"`
+}
+
+// handleBeadsFeed serves the most recently created beads as RSS 2.0.
+// ?status= (default open) scopes the beads and ?limit= (default 20)
+// caps the item count.
+func handleBeadsFeed(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	}
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			sendError(w, fmt.Sprintf("invalid limit %q", s), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	ctx, cancel := withBeadsBudget(r.Context())
+	defer cancel()
+	dirs, _ := limitDirs(beadDirs(), cfg.MaxRigs)
+	raw, _, _ := fetchBeads(ctx, dirs, []string{"--status=" + status}, beadsTTL(cfg))
+
+	type feedBead struct {
+		ID          string `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		CreatedAt   string `json:"created_at"`
+		created     time.Time
+	}
+	beads := make([]feedBead, 0, len(raw))
+	for _, data := range raw {
+		var b feedBead
+		if json.Unmarshal(data, &b) != nil || b.ID == "" {
+			continue
+		}
+		b.created, _ = time.Parse(time.RFC3339, b.CreatedAt)
+		beads = append(beads, b)
+	}
+	sort.SliceStable(beads, func(i, j int) bool { return beads[i].created.After(beads[j].created) })
+	if len(beads) > limit {
+		beads = beads[:limit]
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host
+	feed := rssFeed{Version: "2.0", Channel: rssChannel{
+		Title:       "Rigradar: " + status + " beads",
+		Link:        base + "/",
+		Description: "Most recently created " + status + " beads across the town",
+	}}
+	for _, b := range beads {
+		link := base + "/api/bead/" + url.PathEscape(b.ID)
+		item := rssItem{Title: b.ID + ": " + b.Title, Link: link, GUID: link, Description: b.Description}
+		if !b.created.IsZero() {
+			item.PubDate = b.created.Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	enc.Encode(feed)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleBeadsFeed(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `echo '[
+ {"id":"ri-1","title":"Old","description":"first","created_at":"2026-01-01T00:00:00Z"},
+ {"id":"ri-2","title":"New","description":"<b>second</b>","created_at":"2026-03-01T00:00:00Z"},
+ {"id":"ri-3","title":"Middle","created_at":"2026-02-01T00:00:00Z"}
+]'`)
+
+	w := httptest.NewRecorder()
+	handleBeadsFeed(w, httptest.NewRequest("GET", "http://radar.local/api/beads.rss?limit=2", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/rss+xml") {
+		t.Errorf("Content-Type = %q, want application/rss+xml", ct)
+	}
+	var feed rssFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed is not valid XML: %v\n%s", err, w.Body)
+	}
+	items := feed.Channel.Items
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2 (limit)", len(items))
+	}
+	if items[0].Title != "ri-2: New" || items[1].Title != "ri-3: Middle" {
+		t.Errorf("items = %q, %q; want newest first", items[0].Title, items[1].Title)
+	}
+	if items[0].Link != "http://radar.local/api/bead/ri-2" {
+		t.Errorf("link = %q", items[0].Link)
+	}
+	if items[0].Description != "<b>second</b>" || items[0].PubDate != "Sun, 01 Mar 2026 00:00:00 +0000" {
+		t.Errorf("item = %+v", items[0])
+	}
+
+	w = httptest.NewRecorder()
+	handleBeadsFeed(w, httptest.NewRequest("GET", "/api/beads.rss?limit=x", nil))
+	if w.Code != 400 {
+		t.Errorf("bad limit status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bdHelpCache remembers `bd list --help` so flag support is probed once
+// it has succeeded. A failed probe is retried on the next call.
+type bdHelpCache struct {
+	mu   sync.Mutex
+	ok   bool
+	text string
+}
+
+var bdListHelp = &bdHelpCache{}
+
+// bdListSupports reports whether `bd list` advertises the given flag.
+// If bd is missing or the help can't be read, nothing is supported and
+// callers fall back to filtering server-side.
+func bdListSupports(flag string) bool {
+	h := bdListHelp
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.ok {
+		// execCmdContext bounds the probe by cmdTimeout and runs it like
+		// any other bd call (wrapper, proxy env, town root, slots).
+		out, err := execCmdContext(context.Background(), "bd", []string{"list", "--help"}, nil)
+		if err != nil {
+			return false
+		}
+		var text string
+		if json.Unmarshal(out, &text) != nil {
+			text = string(out)
+		}
+		h.text, h.ok = text, true
+	}
+	return strings.Contains(h.text, flag)
+}
+
+// parseTimeParam accepts RFC3339, a plain date (2006-01-02), or a
+// relative age such as "7d", "2w" or "36h", meaning that long before now.
+func parseTimeParam(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		count, err := strconv.Atoi(s[:n-1])
+		if err == nil && count >= 0 {
+			days := count
+			if s[n-1] == 'w' {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("unparseable time %q (want RFC3339, YYYY-MM-DD, or an age like 7d)", s)
+}
+
+// timeFilter keeps beads whose timestamp field is after (or before) a cutoff.
+type timeFilter struct {
+	field string
+	after bool
+	at    time.Time
+}
+
+// dateRangeParams maps /api/beads query params to the bead field they
+// filter on and the bd flag that can do it natively.
+var dateRangeParams = []struct {
+	param, field, flag string
+	after              bool
+}{
+	{"createdAfter", "created_at", "--created-after", true},
+	{"createdBefore", "created_at", "--created-before", false},
+	{"closedAfter", "closed_at", "--closed-after", true},
+}
+
+// dateRangeArgs turns the date-range query params into bd flags where bd
+// supports them and into server-side filters where it doesn't.
+func dateRangeArgs(get func(string) string, now time.Time) (args []string, filters []timeFilter, err error) {
+	for _, p := range dateRangeParams {
+		v := get(p.param)
+		if v == "" {
+			continue
+		}
+		at, err := parseTimeParam(v, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", p.param, err)
+		}
+		if bdListSupports(p.flag) {
+			args = append(args, p.flag+"="+at.Format(time.RFC3339))
+			continue
+		}
+		filters = append(filters, timeFilter{field: p.field, after: p.after, at: at})
+	}
+	return args, filters, nil
+}
+
+// filterByTime drops beads that fail any filter. A bead without the
+// field (e.g. no closed_at on an open bead) fails.
+func filterByTime(beads []json.RawMessage, filters []timeFilter) []json.RawMessage {
+	if len(filters) == 0 {
+		return beads
+	}
+	out := []json.RawMessage{}
+	for _, raw := range beads {
+		var bead map[string]any
+		if json.Unmarshal(raw, &bead) != nil {
+			continue
+		}
+		keep := true
+		for _, f := range filters {
+			s, _ := bead[f.field].(string)
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil || (f.after && !t.After(f.at)) || (!f.after && !t.Before(f.at)) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, raw)
+		}
+	}
+	return out
+}
+
+// splitList splits a comma-separated query value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// filterByField keeps beads whose string field equals one of values.
+func filterByField(beads []json.RawMessage, field string, values []string) []json.RawMessage {
+	return selectByField(beads, field, values, true)
+}
+
+// rejectByField drops beads whose string field equals one of values.
+func rejectByField(beads []json.RawMessage, field string, values []string) []json.RawMessage {
+	return selectByField(beads, field, values, false)
+}
+
+func selectByField(beads []json.RawMessage, field string, values []string, keep bool) []json.RawMessage {
+	want := make(map[string]bool, len(values))
+	for _, v := range values {
+		want[v] = true
+	}
+	out := []json.RawMessage{}
+	for _, raw := range beads {
+		var bead map[string]any
+		if json.Unmarshal(raw, &bead) != nil {
+			continue
+		}
+		if s, _ := bead[field].(string); want[s] == keep {
+			out = append(out, raw)
+		}
+	}
+	return out
+}
+
+// textFieldNames maps ?qField= names to bead fields.
+var textFieldNames = map[string]string{
+	"id":          "id",
+	"title":       "title",
+	"description": "description",
+	"body":        "description",
+}
+
+// textFields parses ?qField= (comma-separated, default id,title) into bead
+// field names, returning any names it does not know separately.
+func textFields(param string) (fields, unknown []string) {
+	names := splitList(param)
+	if len(names) == 0 {
+		names = []string{"id", "title"}
+	}
+	for _, name := range names {
+		if f, ok := textFieldNames[strings.ToLower(name)]; ok {
+			fields = append(fields, f)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	return fields, unknown
+}
+
+// filterByText keeps beads where any of fields contains q, ignoring case.
+func filterByText(beads []json.RawMessage, q string, fields []string) []json.RawMessage {
+	q = strings.ToLower(q)
+	out := []json.RawMessage{}
+	for _, raw := range beads {
+		var bead map[string]any
+		if json.Unmarshal(raw, &bead) != nil {
+			continue
+		}
+		for _, f := range fields {
+			if s, _ := bead[f].(string); strings.Contains(strings.ToLower(s), q) {
+				out = append(out, raw)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// remapFields renames the top-level keys of a bead, or of each bead in an
+// array, per Config.ResponseFieldNames. Anything else is returned as is.
+func remapFields(raw json.RawMessage, names map[string]string) json.RawMessage {
+	if len(names) == 0 {
+		return raw
+	}
+	var list []map[string]json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		for _, bead := range list {
+			renameKeys(bead, names)
+		}
+		out, _ := json.Marshal(list)
+		return out
+	}
+	var bead map[string]json.RawMessage
+	if json.Unmarshal(raw, &bead) != nil {
+		return raw
+	}
+	renameKeys(bead, names)
+	out, _ := json.Marshal(bead)
+	return out
+}
+
+func renameKeys(bead map[string]json.RawMessage, names map[string]string) {
+	renamed := make(map[string]json.RawMessage)
+	for from, to := range names {
+		if v, ok := bead[from]; ok && to != "" {
+			delete(bead, from)
+			renamed[to] = v
+		}
+	}
+	for k, v := range renamed {
+		bead[k] = v
+	}
+}
+
+// remapBeads applies remapFields to every bead.
+func remapBeads(beads []json.RawMessage, names map[string]string) []json.RawMessage {
+	if len(names) == 0 {
+		return beads
+	}
+	out := make([]json.RawMessage, len(beads))
+	for i, raw := range beads {
+		out[i] = remapFields(raw, names)
+	}
+	return out
+}
+
+// truncateTitles shortens titles longer than max characters to max,
+// ending in an ellipsis, and keeps the original under "fullTitle".
+func truncateTitles(beads []json.RawMessage, max int) []json.RawMessage {
+	out := make([]json.RawMessage, len(beads))
+	for i, raw := range beads {
+		out[i] = raw
+		var bead map[string]json.RawMessage
+		var title string
+		if json.Unmarshal(raw, &bead) != nil || json.Unmarshal(bead["title"], &title) != nil {
+			continue
+		}
+		runes := []rune(title)
+		if len(runes) <= max {
+			continue
+		}
+		bead["fullTitle"] = bead["title"]
+		bead["title"], _ = json.Marshal(string(runes[:max-1]) + "…")
+		if data, err := json.Marshal(bead); err == nil {
+			out[i] = data
+		}
+	}
+	return out
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withFreshBdHelp makes bdListSupports re-probe the (fake) bd binary.
+func withFreshBdHelp(t *testing.T) {
+	t.Helper()
+	orig := bdListHelp
+	bdListHelp = &bdHelpCache{}
+	t.Cleanup(func() { bdListHelp = orig })
+}
+
+func TestParseTimeParam(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2026-03-01T00:00:00Z", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2026-03-01", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{"7d", now.AddDate(0, 0, -7), false},
+		{"2w", now.AddDate(0, 0, -14), false},
+		{"36h", now.Add(-36 * time.Hour), false},
+		{"last tuesday", time.Time{}, true},
+		{"-3d", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeParam(tt.in, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeParam(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeParam(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHandleBeadsDateRangeFallback(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	withFreshBdHelp(t)
+	// This bd has no date flags, so filtering must happen server-side.
+	fakeBin(t, "bd", `if [ "$2" = "--help" ]; then echo "Usage: bd list [--status] [--type]"; exit 0; fi
+case "$*" in *created*) echo "unexpected date flag" >&2; exit 2 ;; esac
+echo '[
+ {"id":"ri-old","status":"closed","created_at":"2026-01-01T00:00:00Z","closed_at":"2026-01-05T00:00:00Z"},
+ {"id":"ri-mid","status":"open","created_at":"2026-02-10T00:00:00Z"},
+ {"id":"ri-new","status":"closed","created_at":"2026-03-01T00:00:00Z","closed_at":"2026-03-02T00:00:00Z"}
+]'
+`)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"createdAfter=2026-02-01", []string{"ri-mid", "ri-new"}},
+		{"createdBefore=2026-02-01T00:00:00Z", []string{"ri-old"}},
+		{"createdAfter=2026-02-01&closedAfter=2026-02-01", []string{"ri-new"}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handleBeads(w, httptest.NewRequest("GET", "/api/beads?"+tt.query, nil))
+		if w.Code != 200 {
+			t.Fatalf("%s: status = %d, body: %s", tt.query, w.Code, w.Body)
+		}
+		var beads []map[string]any
+		json.Unmarshal(w.Body.Bytes(), &beads)
+		var got []string
+		for _, b := range beads {
+			got = append(got, b["id"].(string))
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestHandleBeadsBadDate(t *testing.T) {
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?closedAfter=soonish", nil))
+	if w.Code != 400 {
+		t.Errorf("unparseable date status = %d, want 400", w.Code)
+	}
+}
+
+func TestHandleBeadsMultipleTypes(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `case "$*" in *--type*) echo "multiple types passed to bd" >&2; exit 2 ;; esac
+echo '[
+ {"id":"ri-1","status":"open","issue_type":"task"},
+ {"id":"ri-2","status":"open","issue_type":"epic"},
+ {"id":"ri-3","status":"open","issue_type":"bug"},
+ {"id":"ri-4","status":"closed","issue_type":"event"}
+]'
+`)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?type=task,epic", nil))
+	if w.Code != 200 {
+		t.Fatalf("status = %d, body: %s", w.Code, w.Body)
+	}
+	var beads []map[string]any
+	json.Unmarshal(w.Body.Bytes(), &beads)
+	if len(beads) != 2 {
+		t.Fatalf("got %d beads, want 2: %s", len(beads), w.Body)
+	}
+	for _, b := range beads {
+		if typ := b["issue_type"]; typ != "task" && typ != "epic" {
+			t.Errorf("bead %v has type %v, want only task or epic", b["id"], typ)
+		}
+	}
+}
+
+func TestHandleBeadsArchived(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	withFreshBdHelp(t)
+	fakeBin(t, "bd", `if [ "$2" = "--help" ]; then echo "Usage: bd list [--status]"; exit 0; fi
+echo '[]'
+`)
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?archived=1", nil))
+	if w.Code != 501 {
+		t.Errorf("archived without bd support status = %d, want 501", w.Code)
+	}
+
+	withFreshBdHelp(t)
+	fakeBin(t, "bd", `if [ "$2" = "--help" ]; then echo "Usage: bd list [--status] [--archived]"; exit 0; fi
+case "$*" in *--archived*) echo '[{"id":"ri-old","status":"closed"}]' ;; *) echo '[]' ;; esac
+`)
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?archived=1&status=closed", nil))
+	if w.Code != 200 {
+		t.Fatalf("archived with bd support status = %d, body: %s", w.Code, w.Body)
+	}
+	var beads []map[string]any
+	json.Unmarshal(w.Body.Bytes(), &beads)
+	if len(beads) != 1 || beads[0]["id"] != "ri-old" {
+		t.Errorf("archived beads = %v, want ri-old", beads)
+	}
+}
+
+func TestHandleBeadsUnassigned(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `case "$*" in *--assignee*) echo "assignee=none passed to bd" >&2; exit 2 ;; esac
+echo '[
+ {"id":"ri-1","status":"open","assignee":"max"},
+ {"id":"ri-2","status":"open","assignee":""},
+ {"id":"ri-3","status":"open"},
+ {"id":"ri-4","status":"open","assignee":null}
+]'
+`)
+
+	for _, query := range []string{"assignee=none", "unassigned=1"} {
+		w := httptest.NewRecorder()
+		handleBeads(w, httptest.NewRequest("GET", "/api/beads?"+query, nil))
+		if w.Code != 200 {
+			t.Fatalf("%s: status = %d, body: %s", query, w.Code, w.Body)
+		}
+		var beads []map[string]any
+		json.Unmarshal(w.Body.Bytes(), &beads)
+		if len(beads) != 3 {
+			t.Fatalf("%s: got %d beads, want 3 unassigned: %s", query, len(beads), w.Body)
+		}
+		for _, b := range beads {
+			if b["id"] == "ri-1" {
+				t.Errorf("%s: assigned bead ri-1 should be filtered out", query)
+			}
+		}
+	}
+}
+
+func TestHandleBeadsIncludeClosed(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `case "$*" in *--status=closed*) echo '[{"id":"ri-3","status":"closed"}]'; exit 0 ;; esac
+echo '[
+ {"id":"ri-1","status":"open"},
+ {"id":"ri-2","status":"in_progress"},
+ {"id":"ri-3","status":"closed"}
+]'
+`)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?includeClosed=0", nil))
+	var beads []map[string]any
+	json.Unmarshal(w.Body.Bytes(), &beads)
+	if len(beads) != 2 {
+		t.Fatalf("includeClosed=0 returned %d beads, want 2: %s", len(beads), w.Body)
+	}
+	for _, b := range beads {
+		if b["status"] == "closed" {
+			t.Errorf("closed bead %v returned with includeClosed=0", b["id"])
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?includeClosed=0&status=closed", nil))
+	beads = nil
+	json.Unmarshal(w.Body.Bytes(), &beads)
+	if len(beads) != 1 {
+		t.Errorf("explicit status=closed should win over includeClosed=0: %s", w.Body)
+	}
+}
+
+func TestHandleBeadsTextSearch(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `echo '[
+ {"id":"ri-1","title":"Fix login","description":"Session cookie expires early"},
+ {"id":"ri-2","title":"Cookie banner","description":"Legal wants it"},
+ {"id":"ri-3","title":"Docs","description":"Mention the COOKIE policy"}
+]'`)
+
+	ids := func(query string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleBeads(w, httptest.NewRequest("GET", "/api/beads?"+query, nil))
+		var beads []map[string]any
+		json.Unmarshal(w.Body.Bytes(), &beads)
+		var out []string
+		for _, b := range beads {
+			out = append(out, b["id"].(string))
+		}
+		return out
+	}
+
+	if got := ids("q=cookie"); len(got) != 1 || got[0] != "ri-2" {
+		t.Errorf("q=cookie (id+title) = %v, want [ri-2]", got)
+	}
+	if got := ids("q=cookie&qField=body"); len(got) != 2 || got[0] != "ri-1" || got[1] != "ri-3" {
+		t.Errorf("q=cookie&qField=body = %v, want [ri-1 ri-3]", got)
+	}
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?q=cookie&qField=color&verbose=1", nil))
+	var env struct {
+		Beads    []json.RawMessage `json:"beads"`
+		Warnings []string          `json:"warnings"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &env)
+	if len(env.Beads) != 0 || len(env.Warnings) != 1 {
+		t.Errorf("unknown qField = %s, want no beads and one warning", w.Body)
+	}
+}
+
+func TestResponseFieldNames(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+	cfg := loadConfig()
+	cfg.ResponseFieldNames = map[string]string{"issue_type": "type"}
+	saveConfig(cfg)
+
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","issue_type":"bug","status":"open"}]'`)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?type=bug", nil))
+	var beads []map[string]any
+	json.Unmarshal(w.Body.Bytes(), &beads)
+	if len(beads) != 1 || beads[0]["type"] != "bug" || beads[0]["status"] != "open" {
+		t.Errorf("beads = %v, want issue_type renamed to type", beads)
+	}
+	if _, ok := beads[0]["issue_type"]; ok {
+		t.Error("issue_type should be gone after renaming")
+	}
+
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?groupBy=type", nil))
+	var groups beadGroup
+	json.Unmarshal(w.Body.Bytes(), &groups)
+	leaf := groups.Groups["bug"]
+	if leaf == nil || len(leaf.Beads) != 1 {
+		t.Fatalf("groupBy=type should still group by bd's issue_type: %s", w.Body)
+	}
+	var grouped map[string]any
+	json.Unmarshal(leaf.Beads[0], &grouped)
+	if grouped["type"] != "bug" {
+		t.Errorf("grouped bead = %v, want renamed fields", grouped)
+	}
+
+	req := httptest.NewRequest("GET", "/api/bead/ri-1", nil)
+	w = httptest.NewRecorder()
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","issue_type":"bug"}]'`)
+	handleBeadDetail(w, req)
+	if body := w.Body.String(); body != `[{"id":"ri-1","type":"bug"}]` {
+		t.Errorf("detail = %s, want the renamed field", body)
+	}
+}
+
+func TestHandleBeadsTitleMax(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `echo '[
+ {"id":"ri-1","title":"Rotate the ingress certificates"},
+ {"id":"ri-2","title":"Short"},
+ {"id":"ri-3","title":"Ünïcödé títlé"}
+]'`)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?titleMax=8", nil))
+	var beads []map[string]any
+	json.Unmarshal(w.Body.Bytes(), &beads)
+	if len(beads) != 3 {
+		t.Fatalf("beads = %s, want 3", w.Body)
+	}
+	if beads[0]["title"] != "Rotate …" || beads[0]["fullTitle"] != "Rotate the ingress certificates" {
+		t.Errorf("long title = %q (full %q), want it truncated to 8 characters", beads[0]["title"], beads[0]["fullTitle"])
+	}
+	if _, ok := beads[1]["fullTitle"]; ok || beads[1]["title"] != "Short" {
+		t.Errorf("short title = %v, want it untouched", beads[1])
+	}
+	if beads[2]["title"] != "Ünïcödé…" {
+		t.Errorf("title = %q, want truncation by character, not byte", beads[2]["title"])
+	}
+
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?titleMax=8&view=sidebar", nil))
+	var sidebar []map[string]any
+	json.Unmarshal(w.Body.Bytes(), &sidebar)
+	if len(sidebar) != 3 || sidebar[0]["title"] != "Rotate …" || sidebar[0]["fullTitle"] != "Rotate the ingress certificates" {
+		t.Errorf("sidebar = %s, want truncated titles with fullTitle", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?titleMax=0", nil))
+	if w.Code != 400 {
+		t.Errorf("titleMax=0 status = %d, want 400", w.Code)
+	}
+}
+
+func TestBdListSupportsRetriesAndTimesOut(t *testing.T) {
+	withFreshBdHelp(t)
+	orig := cmdTimeout
+	defer func() { cmdTimeout = orig }()
+	cmdTimeout = 50 * time.Millisecond
+
+	fakeBin(t, "bd", "exec sleep 5\n")
+	start := time.Now()
+	if bdListSupports("--archived") {
+		t.Error("a hanging bd should support nothing")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("probe took %s, want it bounded by cmdTimeout", elapsed)
+	}
+
+	fakeBin(t, "bd", "echo 'Flags:'; echo '  --archived   include archived'\n")
+	if !bdListSupports("--archived") {
+		t.Error("a failed probe should not be cached: the next call should see --archived")
+	}
+}
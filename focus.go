@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// focusHint explains an empty /api/focus when there is nobody to focus on.
+const focusHint = "no polecat identity is configured (gt whoami); /api/ready lists ready work for everyone"
+
+// handleFocus answers "what should I work on right now": the ready beads
+// from `gt ready` assigned to the current polecat, minus closed, blocked
+// and still-blocked ones, highest priority first and oldest first within
+// a priority.
+func handleFocus(w http.ResponseWriter, r *http.Request) {
+	name := identityName(currentIdentity())
+	if name == "" {
+		sendJSON(w, map[string]any{"polecat": nil, "beads": []any{}, "hint": focusHint}, http.StatusOK)
+		return
+	}
+
+	data, err := execCmdContext(r.Context(), "gt", []string{"ready", "--json"}, nil)
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+	var mine []map[string]any
+	for _, bead := range readyFor(data, name) {
+		status, _ := bead["status"].(string)
+		if a, _ := bead["assignee"].(string); a == "" || status == "closed" || status == "blocked" {
+			continue
+		}
+		mine = append(mine, bead)
+	}
+
+	unblocked := make([]bool, len(mine))
+	var wg sync.WaitGroup
+	for i, bead := range mine {
+		wg.Add(1)
+		go func(i int, bead map[string]any) {
+			defer wg.Done()
+			unblocked[i] = !stillBlocked(r, bead)
+		}(i, bead)
+	}
+	wg.Wait()
+
+	beads := []map[string]any{}
+	for i, bead := range mine {
+		if unblocked[i] {
+			beads = append(beads, bead)
+		}
+	}
+	sortFocus(beads)
+	sendJSON(w, map[string]any{"polecat": name, "beads": beads}, http.StatusOK)
+}
+
+// stillBlocked reports whether any of the bead's blockers is open. gt
+// ready usually omits dependencies, in which case the bead is looked up
+// with bd show; if that fails too the bead is kept. A blocker that can't
+// be read counts as blocking.
+func stillBlocked(r *http.Request, bead map[string]any) bool {
+	if _, ok := bead["dependencies"]; !ok {
+		id, _ := bead["id"].(string)
+		full, err := showBead(r, id)
+		if err != nil {
+			return false
+		}
+		bead = full
+	}
+	for _, id := range blockerIDs(bead) {
+		dep, err := showBead(r, id)
+		if err != nil {
+			return true
+		}
+		if status, _ := dep["status"].(string); status != "closed" {
+			return true
+		}
+	}
+	return false
+}
+
+// sortFocus orders beads by priority, then by created_at, oldest first.
+// Beads without a usable priority or creation time sort last.
+func sortFocus(beads []map[string]any) {
+	rank := func(bead map[string]any) int {
+		if p, ok := beadPriority(bead["priority"]); ok {
+			return p
+		}
+		return 1 << 30
+	}
+	created := func(bead map[string]any) time.Time {
+		s, _ := bead["created_at"].(string)
+		t, _ := time.Parse(time.RFC3339, strings.TrimSpace(s))
+		return t
+	}
+	sort.SliceStable(beads, func(i, j int) bool {
+		if pi, pj := rank(beads[i]), rank(beads[j]); pi != pj {
+			return pi < pj
+		}
+		ci, cj := created(beads[i]), created(beads[j])
+		if ci.IsZero() || cj.IsZero() {
+			return !ci.IsZero() && cj.IsZero()
+		}
+		return ci.Before(cj)
+	})
+}
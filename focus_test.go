@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleFocus(t *testing.T) {
+	resetIdentityCache(t)
+	origMap, origRoot := prefixMap, townRoot
+	defer func() { prefixMap, townRoot = origMap, origRoot }()
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"ri": filepath.Join(townRoot, ".beads")}
+	fakeBin(t, "gt", `case "$1" in
+whoami) echo '{"name":"nux"}' ;;
+ready) echo '[
+ {"id":"ri-1","assignee":"nux","status":"open","priority":2,"created_at":"2026-01-01T00:00:00Z"},
+ {"id":"ri-2","assignee":"rigradar/polecats/nux","status":"open","priority":1,"created_at":"2026-03-01T00:00:00Z"},
+ {"id":"ri-3","assignee":"nux","status":"open","priority":1,"created_at":"2026-02-01T00:00:00Z"},
+ {"id":"ri-4","assignee":"","status":"open","priority":0},
+ {"id":"ri-5","assignee":"slit","status":"open","priority":0},
+ {"id":"ri-6","assignee":"nux","status":"closed","priority":0},
+ {"id":"ri-7","assignee":"nux","status":"open","priority":0,"dependencies":[{"depends_on_id":"ri-9","type":"blocks"}]},
+ {"id":"ri-8","assignee":"nux","status":"open","priority":0,"dependencies":[{"depends_on_id":"ri-10","type":"blocks"}]}
+]' ;;
+esac`)
+	fakeBin(t, "bd", `case "$2" in
+ri-9) echo '[{"id":"ri-9","status":"open"}]' ;;
+ri-10) echo '[{"id":"ri-10","status":"closed"}]' ;;
+*) echo "[{\"id\":\"$2\",\"dependencies\":[]}]" ;;
+esac`)
+
+	w := httptest.NewRecorder()
+	handleFocus(w, httptest.NewRequest("GET", "/api/focus", nil))
+	if w.Code != 200 {
+		t.Fatalf("focus status = %d %s", w.Code, w.Body)
+	}
+	var result struct {
+		Polecat string           `json:"polecat"`
+		Beads   []map[string]any `json:"beads"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	var ids []string
+	for _, b := range result.Beads {
+		ids = append(ids, b["id"].(string))
+	}
+	want := []string{"ri-8", "ri-3", "ri-2", "ri-1"}
+	if result.Polecat != "nux" || len(ids) != len(want) {
+		t.Fatalf("focus = %s %v, want nux with %v", result.Polecat, ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("focus order = %v, want %v", ids, want)
+		}
+	}
+}
+
+func TestHandleFocusNoIdentity(t *testing.T) {
+	resetIdentityCache(t)
+	fakeBin(t, "gt", "echo 'no identity configured' >&2; exit 1\n")
+
+	w := httptest.NewRecorder()
+	handleFocus(w, httptest.NewRequest("GET", "/api/focus", nil))
+	if w.Code != 200 {
+		t.Fatalf("focus without identity status = %d, want 200", w.Code)
+	}
+	var result map[string]any
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if beads, ok := result["beads"].([]any); !ok || len(beads) != 0 {
+		t.Errorf("beads = %v, want []", result["beads"])
+	}
+	if result["hint"] == nil || result["hint"] == "" {
+		t.Error("expected a hint explaining the empty list")
+	}
+}
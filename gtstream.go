@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// handleGtStream runs an allowlisted `gt <command>` and streams its
+// combined stdout/stderr to the client as server-sent events: one "data:"
+// event per line, then an "exit" event carrying the exit code. Closing the
+// connection kills the command.
+//
+// Unlike execCmdContext there is no 15s timeout and no cmdSlots slot, as
+// these commands are expected to run for minutes.
+func handleGtStream(w http.ResponseWriter, r *http.Request) {
+	command := r.PathValue("command")
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	if !slices.Contains(cfg.GtStreamCommands, command) {
+		sendError(w, fmt.Sprintf("gt %s is not in gtStreamCommands", command), http.StatusForbidden)
+		return
+	}
+
+	argv := wrapCommand(execWrapper, "gt", []string{command})
+	cmd := exec.CommandContext(r.Context(), argv[0], argv[1:]...)
+	cmd.Dir = townRoot
+	cmd.Env = commandEnv(nil)
+	cmd.WaitDelay = 2 * time.Second
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		done <- err
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	flush := func() { http.NewResponseController(w).Flush() }
+	flush()
+
+	sc := bufio.NewScanner(pr)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		fmt.Fprintf(w, "data: %s\n\n", strings.TrimRight(sc.Text(), "\r"))
+		flush()
+	}
+	// Drain anything left after an over-long line so Wait can finish.
+	io.Copy(io.Discard, pr)
+
+	code := 0
+	if err := <-done; err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", err)
+			flush()
+			return
+		}
+		code = exitErr.ExitCode()
+	}
+	fmt.Fprintf(w, "event: exit\ndata: {\"code\":%d}\n\n", code)
+	flush()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleGtStream(t *testing.T) {
+	origPath, origRoot := configPath, townRoot
+	defer func() { configPath, townRoot = origPath, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	cfg := loadConfig()
+	cfg.GtStreamCommands = []string{"sync"}
+	saveConfig(cfg)
+
+	fakeBin(t, "gt", `echo "syncing $1"
+echo "warning: slow" >&2
+echo done
+exit 3
+`)
+
+	req := httptest.NewRequest("POST", "/api/gt/sync/stream", nil)
+	req.SetPathValue("command", "sync")
+	w := httptest.NewRecorder()
+	handleGtStream(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"data: syncing sync\n\n", "data: warning: slow\n\n", "data: done\n\n", "event: exit\ndata: {\"code\":3}\n\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream missing %q:\n%s", want, body)
+		}
+	}
+
+	req = httptest.NewRequest("POST", "/api/gt/nuke/stream", nil)
+	req.SetPathValue("command", "nuke")
+	w = httptest.NewRecorder()
+	handleGtStream(w, req)
+	if w.Code != 403 {
+		t.Errorf("unlisted command status = %d, want 403", w.Code)
+	}
+}
+
+func TestHandleGtStreamCancel(t *testing.T) {
+	origPath, origRoot := configPath, townRoot
+	defer func() { configPath, townRoot = origPath, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	cfg := loadConfig()
+	cfg.GtStreamCommands = []string{"sync"}
+	saveConfig(cfg)
+	fakeBin(t, "gt", "echo started; exec sleep 30\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("POST", "/api/gt/sync/stream", nil).WithContext(ctx)
+	req.SetPathValue("command", "sync")
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	handleGtStream(httptest.NewRecorder(), req)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("handler ran %v after the client went away", elapsed)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// identityTTL is how long a `gt whoami` answer is reused. Identity rarely
+// changes, so this mostly saves a subprocess per page load.
+const identityTTL = 5 * time.Minute
+
+var identityCache struct {
+	mu      sync.Mutex
+	value   json.RawMessage
+	fetched time.Time
+}
+
+// currentIdentity returns the polecat/rig identity reported by
+// `gt whoami --json`, or nil when no identity is configured.
+func currentIdentity() json.RawMessage {
+	identityCache.mu.Lock()
+	defer identityCache.mu.Unlock()
+	if !identityCache.fetched.IsZero() && time.Since(identityCache.fetched) < identityTTL {
+		return identityCache.value
+	}
+
+	data, err := execCmd("gt", []string{"whoami", "--json"}, nil)
+	if err != nil {
+		log.Printf("identity: gt whoami failed: %v", err)
+		data = nil
+	} else {
+		// Non-JSON output comes back wrapped as a string; that is not an identity.
+		var probe any
+		if json.Unmarshal(data, &probe) != nil {
+			data = nil
+		} else if _, ok := probe.(map[string]any); !ok {
+			data = nil
+		}
+	}
+	identityCache.value = data
+	identityCache.fetched = time.Now()
+	return data
+}
+
+// identityName extracts a display name from an identity object, trying
+// the field names gt has used for it.
+func identityName(identity json.RawMessage) string {
+	var fields map[string]any
+	if json.Unmarshal(identity, &fields) != nil {
+		return ""
+	}
+	for _, key := range []string{"name", "polecat", "identity", "actor"} {
+		if s, ok := fields[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func handleIdentity(w http.ResponseWriter, r *http.Request) {
+	identity := currentIdentity()
+	if identity == nil {
+		sendJSON(w, map[string]any{"identity": nil, "name": nil}, http.StatusOK)
+		return
+	}
+	sendJSON(w, map[string]any{"identity": identity, "name": identityName(identity)}, http.StatusOK)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func resetIdentityCache(t *testing.T) {
+	t.Helper()
+	identityCache.value, identityCache.fetched = nil, time.Time{}
+	t.Cleanup(func() { identityCache.value, identityCache.fetched = nil, time.Time{} })
+}
+
+func TestHandleIdentity(t *testing.T) {
+	resetIdentityCache(t)
+	fakeBin(t, "gt", `echo '{"name":"nux","rig":"rigradar","role":"polecat"}'`+"\n")
+
+	w := httptest.NewRecorder()
+	handleIdentity(w, httptest.NewRequest("GET", "/api/identity", nil))
+	if w.Code != 200 {
+		t.Fatalf("identity status = %d", w.Code)
+	}
+	var result map[string]any
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result["name"] != "nux" {
+		t.Errorf("identity name = %v, want nux", result["name"])
+	}
+	if id, ok := result["identity"].(map[string]any); !ok || id["rig"] != "rigradar" {
+		t.Errorf("identity object = %v, want the gt whoami output", result["identity"])
+	}
+}
+
+func TestHandleIdentityNotConfigured(t *testing.T) {
+	resetIdentityCache(t)
+	fakeBin(t, "gt", "echo 'no identity configured' >&2; exit 1\n")
+
+	w := httptest.NewRecorder()
+	handleIdentity(w, httptest.NewRequest("GET", "/api/identity", nil))
+	if w.Code != 200 {
+		t.Fatalf("unconfigured identity status = %d, want 200", w.Code)
+	}
+	var result map[string]any
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if v, ok := result["identity"]; !ok || v != nil {
+		t.Errorf("identity = %v, want null", v)
+	}
+}
@@ -1,30 +1,200 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
-	_ "embed"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"html"
+	"io"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
 //go:embed index.html
 var indexHTML []byte
 
+// newBeadHTML is the quick-create form served at /new.
+//
+//go:embed new.html
+var newBeadHTML []byte
+
+// staticFS holds extra UI assets (CSS, JS, images) served under /static/.
+//
+//go:embed static
+var staticFS embed.FS
+
 type Config struct {
 	Filters         Filters      `json:"filters"`
 	Server          ServerConfig `json:"server"`
 	RefreshInterval int          `json:"refreshInterval"`
+	// ReadOnly disables every mutation endpoint.
+	ReadOnly bool `json:"readOnly"`
+	// EnabledMutations lists the mutation endpoints that may run
+	// (see knownMutations). Nil means all of them.
+	EnabledMutations []string `json:"enabledMutations,omitempty"`
+	// MaxConcurrentCommands caps how many bd/gt subprocesses run at once.
+	MaxConcurrentCommands int `json:"maxConcurrentCommands"`
+	// BeadsBudgetMs bounds the whole /api/beads fan-out; rigs that have not
+	// answered by then are left out and the response is marked partial.
+	BeadsBudgetMs int `json:"beadsBudgetMs"`
+	// TrendSampleMs is how often the open bead count is sampled for
+	// /api/trends; 0 uses RefreshInterval.
+	TrendSampleMs int `json:"trendSampleMs,omitempty"`
+	// TrendBufferSize is how many trend samples are kept in memory.
+	// HistoryMaxEntries takes precedence.
+	TrendBufferSize int `json:"trendBufferSize,omitempty"`
+	// HistoryMaxEntries caps each in-memory history buffer (the trend
+	// samples); 0 falls back to TrendBufferSize, then 120.
+	HistoryMaxEntries int `json:"historyMaxEntries,omitempty"`
+	// HistoryRetentionMinutes drops history samples older than this; 0
+	// keeps a day.
+	HistoryRetentionMinutes int `json:"historyRetentionMinutes,omitempty"`
+	// PrefixOverrides maps a bead prefix to an absolute beads directory and
+	// takes precedence over routes.jsonl and the rig directory scan.
+	PrefixOverrides map[string]string `json:"prefixOverrides,omitempty"`
+	// InlineConfig embeds the current config in the served page so the UI
+	// can skip its initial /api/config round-trip.
+	InlineConfig bool `json:"inlineConfig,omitempty"`
+	// RequireCloseReason makes the close endpoint reject requests without
+	// a non-empty reason.
+	RequireCloseReason bool `json:"requireCloseReason,omitempty"`
+	// IdleTimeoutMinutes shuts the server down after that long without
+	// requests. 0 never shuts down.
+	IdleTimeoutMinutes int `json:"idleTimeoutMinutes,omitempty"`
+	// IdleIgnorePaths are request paths that do not count as activity.
+	IdleIgnorePaths []string `json:"idleIgnorePaths,omitempty"`
+	// BannerMessage, when non-empty, is shown at the top of the UI.
+	BannerMessage string `json:"bannerMessage,omitempty"`
+	// BannerLevel styles the banner: "info" (default) or "warn".
+	BannerLevel string `json:"bannerLevel,omitempty"`
+	// Theme is the UI color scheme: "dark" (default), "light", or "auto"
+	// to follow the browser's prefers-color-scheme.
+	Theme string `json:"theme,omitempty"`
+	// WatchMode selects how routes.jsonl and beads.db changes are noticed:
+	// "poll" (default) or "off".
+	WatchMode string `json:"watchMode,omitempty"`
+	// WatchPollMs is the poll interval when WatchMode is "poll".
+	WatchPollMs int `json:"watchPollMs,omitempty"`
+	// PlainNotFound answers unknown non-API paths with a plain 404 instead
+	// of the UI page (which the UI uses for client-side routing).
+	PlainNotFound bool `json:"plainNotFound,omitempty"`
+	// ExtraHeaders are added to every response (e.g. X-Frame-Options).
+	// They are read at startup and cannot replace Content-Type or CORS
+	// headers.
+	ExtraHeaders map[string]string `json:"extraHeaders,omitempty"`
+	// LogFormat is "text" (default) or "json" for one JSON object per
+	// log line. Read at startup.
+	LogFormat string `json:"logFormat,omitempty"`
+	// BdReadCommands are the bd subcommands GET /api/bd/{subcommand} may
+	// run. Only list read-only commands here.
+	BdReadCommands []string `json:"bdReadCommands,omitempty"`
+	// GtStreamCommands are the gt commands POST /api/gt/{command}/stream
+	// may run. Empty (the default) disables the endpoint.
+	GtStreamCommands []string `json:"gtStreamCommands,omitempty"`
+	// ReadTimeoutSec and WriteTimeoutSec are the server's read and write
+	// timeouts; 0 disables them. Streaming routes clear their own write
+	// deadline (see streaming).
+	ReadTimeoutSec  int `json:"readTimeoutSec"`
+	WriteTimeoutSec int `json:"writeTimeoutSec"`
+	// AllowPublicBind lets Server.Host be a non-loopback address. Without
+	// it rigradar refuses to start rather than expose the dashboard.
+	AllowPublicBind bool `json:"allowPublicBind,omitempty"`
+	// WatchedBeads are bead ids pinned by the user, shown by /api/watched.
+	WatchedBeads []string `json:"watchedBeads,omitempty"`
+	// MaxRigs caps how many beads directories /api/beads fans out to, as a
+	// guard against a runaway rig scan. 0 is unlimited.
+	MaxRigs int `json:"maxRigs,omitempty"`
+	// BeadsCacheMs reuses a /api/beads fan-out for identical queries made
+	// within that many milliseconds. 0 disables the cache.
+	BeadsCacheMs int `json:"beadsCacheMs,omitempty"`
+	// PrewarmBeads fills the cache at startup (needs BeadsCacheMs). While
+	// it runs, /api/beads answers 503 with Retry-After unless
+	// WarmupServeCold is set.
+	PrewarmBeads        bool `json:"prewarmBeads,omitempty"`
+	WarmupServeCold     bool `json:"warmupServeCold,omitempty"`
+	WarmupRetryAfterSec int  `json:"warmupRetryAfterSec,omitempty"`
+	// BackgroundRefreshSec re-runs cached fan-outs on this interval (needs
+	// BeadsCacheMs), so clients read a warm cache instead of spawning bd.
+	// Queries nobody has read for BackgroundIdleSec (default 300) are no
+	// longer refreshed. Read at startup.
+	BackgroundRefreshSec int `json:"backgroundRefreshSec,omitempty"`
+	BackgroundIdleSec    int `json:"backgroundIdleSec,omitempty"`
+	// ExecWrapper runs bd/gt through another command, e.g.
+	// "nix develop -c {cmd}". Split on whitespace, no shell. Read at
+	// startup.
+	ExecWrapper string `json:"execWrapper,omitempty"`
+	// ProxyEnv sets HTTP_PROXY, HTTPS_PROXY, NO_PROXY or ALL_PROXY (either
+	// case) for bd/gt subprocesses. Unset inherits rigradar's environment.
+	// Read at startup.
+	ProxyEnv map[string]string `json:"proxyEnv,omitempty"`
+	// BulkConfirmAbove is how many ids a bulk operation may touch before
+	// the request must carry a matching "confirm" count (or "force").
+	BulkConfirmAbove int `json:"bulkConfirmAbove"`
+	// PortFallback is how many following ports to try when the configured
+	// one is taken. 0 exits instead.
+	PortFallback int `json:"portFallback,omitempty"`
+	// InstanceCheck controls the startup probe for a rigradar already
+	// answering on the configured address: "warn" (default) logs it,
+	// "refuse" exits, "off" skips the probe. Read at startup.
+	InstanceCheck string `json:"instanceCheck,omitempty"`
+	// H2C additionally serves HTTP/2 without TLS (prior knowledge) for
+	// local tooling; browsers keep using HTTP/1.1. Read at startup.
+	H2C bool `json:"h2c,omitempty"`
+	// DefaultRig is the prefix used by rig-scoped operations (creating a
+	// bead, /api/bd) that name no rig. Unset or unknown means the town.
+	DefaultRig string `json:"defaultRig,omitempty"`
+	// AutoRefreshStatuses are the bead statuses the dashboard re-fetches on
+	// its auto-refresh timer; the manual Refresh button always fetches
+	// all of them. Empty re-fetches everything.
+	AutoRefreshStatuses []string `json:"autoRefreshStatuses,omitempty"`
+	// P0AlertThreshold raises an /api/alerts alert when more than this many
+	// P0 beads are open; 0 is off. Shorthand for AlertThresholds[0].
+	P0AlertThreshold int `json:"p0AlertThreshold,omitempty"`
+	// AlertThresholds does the same per priority level (0-4).
+	AlertThresholds map[int]int `json:"alertThresholds,omitempty"`
+	// DirectRead reads bead lists and details from each rig's beads.db via
+	// the sqlite3 CLI instead of bd, falling back to bd per query. Read at
+	// startup.
+	DirectRead bool `json:"directRead,omitempty"`
+	// ListWrapperKeys are the object keys a wrapped `bd list` array may
+	// sit under, tried in order. Empty uses beads, items, data, results.
+	// Read at startup.
+	ListWrapperKeys []string `json:"listWrapperKeys,omitempty"`
+	// MissingGraceSec is how long a rig must keep failing its health probe
+	// before /api/rigs reports it broken. 0 reports at once.
+	MissingGraceSec int `json:"missingGraceSec,omitempty"`
+	// AlwaysShowRigs are prefixes /api/rigs, /api/overview and
+	// /api/overview/rigs list even without beads (or a beads directory),
+	// with zero counts. Disabled routes stay hidden.
+	AlwaysShowRigs []string `json:"alwaysShowRigs,omitempty"`
+	// ResponseFieldNames renames top-level bead fields (bd name to output
+	// name) in /api/beads and /api/bead/{id} responses. Empty passes bd's
+	// names through.
+	ResponseFieldNames map[string]string `json:"responseFieldNames,omitempty"`
+	// Templates are named defaults for POST /api/bead?template=.
+	Templates map[string]beadFields `json:"templates,omitempty"`
 }
 
 type Filters struct {
@@ -91,29 +261,106 @@ func findTownRoot(start string) string {
 	return start
 }
 
-// buildRigPrefixNameMap reads routes.jsonl and returns a prefix -> rig name mapping
-// (for frontend display, e.g. "ri" -> "rigradar")
-func buildRigPrefixNameMap() map[string]string {
-	routesPath := filepath.Join(townRoot, ".beads", "routes.jsonl")
-	m := make(map[string]string)
+// route is one line of the town's .beads/routes.jsonl.
+type route struct {
+	Prefix string `json:"prefix"`
+	Path   string `json:"path"`
+	// Enabled and Archived let a town keep retired rigs in routes.jsonl
+	// without rigradar fetching their beads.
+	Enabled  *bool `json:"enabled,omitempty"`
+	Archived bool  `json:"archived,omitempty"`
+}
+
+// disabled reports whether the route is marked "enabled": false or
+// "archived": true.
+func (rt route) disabled() bool {
+	return rt.Archived || (rt.Enabled != nil && !*rt.Enabled)
+}
+
+// disabledRoutes lists the routes.jsonl entries that are skipped by the
+// prefix map, for /api/diagnostics.
+func disabledRoutes() []route {
+	disabled := []route{}
+	for _, rt := range readRoutes() {
+		if rt.disabled() {
+			disabled = append(disabled, rt)
+		}
+	}
+	return disabled
+}
 
-	data, err := os.ReadFile(routesPath)
+// readRoutes parses townRoot/.beads/routes.jsonl, skipping blank and
+// malformed lines. A leading UTF-8 BOM and CRLF line endings are
+// tolerated. A missing file yields no routes.
+func readRoutes() []route {
+	data, err := os.ReadFile(filepath.Join(townRoot, ".beads", "routes.jsonl"))
 	if err != nil {
-		return m
+		return nil
 	}
+	text := strings.TrimPrefix(string(data), "\uFEFF")
 
-	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+	var routes []route
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
-		var route struct {
-			Prefix string `json:"prefix"`
-			Path   string `json:"path"`
+		var rt route
+		if json.Unmarshal([]byte(line), &rt) != nil {
+			continue
+		}
+		routes = append(routes, rt)
+	}
+	return routes
+}
+
+// readRigIgnore returns the glob patterns in townRoot/.rigradarignore, one
+// per line. Blank lines and # comments are skipped, a trailing / is
+// dropped, and invalid patterns are logged and skipped.
+func readRigIgnore() []string {
+	data, err := os.ReadFile(filepath.Join(townRoot, ".rigradarignore"))
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(strings.TrimSpace(line), "/")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
-		if json.Unmarshal([]byte(line), &route) != nil {
+		if _, err := path.Match(line, ""); err != nil {
+			log.Printf("warning: .rigradarignore: invalid pattern %q: %v", line, err)
 			continue
 		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// rigIgnored reports whether a rig directory name matches one of patterns.
+func rigIgnored(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// routeBeadsDir returns the beads directory a route points at.
+func routeBeadsDir(rt route) string {
+	if rt.Path == "." {
+		return filepath.Join(townRoot, ".beads")
+	}
+	return filepath.Join(townRoot, rt.Path, ".beads")
+}
+
+// buildRigPrefixNameMap reads routes.jsonl and returns a prefix -> rig name mapping
+// (for frontend display, e.g. "ri" -> "rigradar")
+func buildRigPrefixNameMap() map[string]string {
+	m := make(map[string]string)
+
+	for _, route := range readRoutes() {
 		prefix := strings.TrimSuffix(route.Prefix, "-")
 		key := prefix
 		if idx := strings.Index(prefix, "-"); idx > 0 {
@@ -134,79 +381,112 @@ func buildRigPrefixNameMap() map[string]string {
 func buildPrefixMap() map[string]string {
 	townBeadsDir := filepath.Join(townRoot, ".beads")
 	m := map[string]string{"hq": townBeadsDir}
+	// Beads dirs of disabled routes, kept out of the directory scan too.
+	skip := make(map[string]bool)
 
 	// Use routes.jsonl for prefix resolution
-	routesPath := filepath.Join(townBeadsDir, "routes.jsonl")
-	data, err := os.ReadFile(routesPath)
-	if err == nil {
-		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-			var route struct {
-				Prefix string `json:"prefix"`
-				Path   string `json:"path"`
+	for _, route := range readRoutes() {
+		if route.disabled() {
+			skip[routeBeadsDir(route)] = true
+			continue
+		}
+		prefix := strings.TrimSuffix(route.Prefix, "-")
+		rigPath := route.Path
+		beadsDir := routeBeadsDir(route)
+		m[prefix] = beadsDir
+		// Also map first segment for multi-segment prefixes
+		if idx := strings.Index(prefix, "-"); idx > 0 {
+			firstSeg := prefix[:idx]
+			if _, exists := m[firstSeg]; !exists {
+				m[firstSeg] = beadsDir
 			}
-			if json.Unmarshal([]byte(line), &route) != nil {
+		}
+		if rigPath != "." {
+			m[rigPath] = beadsDir
+		}
+	}
+
+	// Also scan for rig directories (fallback), minus .rigradarignore
+	ignore := readRigIgnore()
+	if entries, err := os.ReadDir(townRoot); err == nil {
+		for _, e := range entries {
+			if !e.IsDir() || rigIgnored(e.Name(), ignore) {
 				continue
 			}
-			prefix := strings.TrimSuffix(route.Prefix, "-")
-			rigPath := route.Path
-			beadsDir := townBeadsDir
-			if rigPath != "." {
-				beadsDir = filepath.Join(townRoot, rigPath, ".beads")
+			beadsDir := filepath.Join(townRoot, e.Name(), ".beads")
+			if skip[beadsDir] {
+				continue
 			}
-			m[prefix] = beadsDir
-			// Also map first segment for multi-segment prefixes
-			if idx := strings.Index(prefix, "-"); idx > 0 {
-				firstSeg := prefix[:idx]
-				if _, exists := m[firstSeg]; !exists {
-					m[firstSeg] = beadsDir
-				}
+			dbPath := filepath.Join(beadsDir, "beads.db")
+			if _, err := os.Stat(dbPath); err != nil {
+				continue
 			}
-			if rigPath != "." {
-				m[rigPath] = beadsDir
+			if _, exists := m[e.Name()]; !exists {
+				m[e.Name()] = beadsDir
 			}
 		}
 	}
 
-	// Also scan for rig directories (fallback)
-	entries, err := os.ReadDir(townRoot)
-	if err != nil {
-		return m
+	// Explicit overrides from config win over everything discovered above
+	configMu.RLock()
+	overrides := loadConfig().PrefixOverrides
+	configMu.RUnlock()
+	for prefix, dir := range overrides {
+		m[strings.TrimSuffix(prefix, "-")] = dir
 	}
 
-	for _, e := range entries {
-		if !e.IsDir() {
-			continue
-		}
-		beadsDir := filepath.Join(townRoot, e.Name(), ".beads")
-		dbPath := filepath.Join(beadsDir, "beads.db")
-		if _, err := os.Stat(dbPath); err != nil {
-			continue
-		}
-		if _, exists := m[e.Name()]; !exists {
-			m[e.Name()] = beadsDir
+	return m
+}
+
+// warnMissingOverrides logs every PrefixOverrides entry whose directory
+// does not exist, so typos surface at startup rather than as failed bd calls.
+func warnMissingOverrides(cfg Config) {
+	for prefix, dir := range cfg.PrefixOverrides {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			log.Printf("warning: prefixOverrides[%q] = %q does not exist", prefix, dir)
 		}
 	}
+}
 
-	return m
+// defaultRig is Config.DefaultRig when it names a known prefix, else hq.
+func defaultRig(cfg Config) string {
+	if p := strings.TrimSuffix(cfg.DefaultRig, "-"); p != "" {
+		if _, ok := currentPrefixMap()[p]; ok {
+			return p
+		}
+	}
+	return "hq"
 }
 
 func beadsDirForID(beadID string) string {
+	dir, _ := lookupBeadsDir(beadID)
+	return dir
+}
+
+// lookupBeadsDir is beadsDirForID that also reports whether the id's
+// prefix was actually resolved rather than falling back to the town.
+func lookupBeadsDir(beadID string) (string, bool) {
+	if _, dir, ok := resolveBeadPrefix(beadID); ok {
+		return dir, true
+	}
+	return filepath.Join(townRoot, ".beads"), false
+}
+
+// resolveBeadPrefix splits off the id's prefix (everything before the
+// first dash) and looks it up in the prefix map.
+func resolveBeadPrefix(beadID string) (prefix, dir string, ok bool) {
 	dash := strings.Index(beadID, "-")
-	if dash > 0 {
-		prefix := beadID[:dash]
-		if dir, ok := prefixMap[prefix]; ok {
-			return dir
-		}
+	if dash <= 0 {
+		return "", "", false
 	}
-	return filepath.Join(townRoot, ".beads")
+	prefix = beadID[:dash]
+	dir, ok = currentPrefixMap()[prefix]
+	return prefix, dir, ok
 }
 
-func loadConfig() Config {
-	cfg := Config{
+// defaultConfig is the config used for any field config.json leaves out.
+func defaultConfig() Config {
+	return Config{
 		Filters: Filters{
 			HideSystemBeads:      true,
 			HideEvents:           true,
@@ -214,10 +494,20 @@ func loadConfig() Config {
 			HideMaintenanceWisps: true,
 			HideHQBeads:          true,
 		},
-		Server:          ServerConfig{Port: 9292, Host: "localhost"},
-		RefreshInterval: 30000,
+		Server:                ServerConfig{Port: 9292, Host: "localhost"},
+		RefreshInterval:       30000,
+		MaxConcurrentCommands: 8,
+		BeadsBudgetMs:         20000,
+		IdleIgnorePaths:       []string{"/health", "/livez", "/readyz"},
+		BdReadCommands:        []string{"stats", "blocked", "ready"},
+		ReadTimeoutSec:        30,
+		WriteTimeoutSec:       30,
+		BulkConfirmAbove:      5,
 	}
+}
 
+func loadConfig() Config {
+	cfg := defaultConfig()
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return cfg
@@ -234,26 +524,149 @@ func saveConfig(cfg Config) error {
 	return os.WriteFile(configPath, append(data, '\n'), 0644)
 }
 
-func execCmd(name string, args []string, env map[string]string) (json.RawMessage, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+// knownMutations are the write operations that EnabledMutations can toggle.
+var knownMutations = []string{"create", "update", "close", "gt"}
 
-	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Dir = townRoot
+// enabledMutations reports, for each of knownMutations, whether cfg allows it.
+func enabledMutations(cfg Config) map[string]bool {
+	mutations := make(map[string]bool, len(knownMutations))
+	for _, m := range knownMutations {
+		mutations[m] = mutationEnabled(cfg, m)
+	}
+	return mutations
+}
+
+func mutationEnabled(cfg Config, name string) bool {
+	if cfg.ReadOnly {
+		return false
+	}
+	if cfg.EnabledMutations == nil {
+		return true
+	}
+	for _, m := range cfg.EnabledMutations {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// requireMutation wraps a mutation handler so it returns 403 when the
+// mutation is disabled by ReadOnly or EnabledMutations.
+func requireMutation(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		configMu.RLock()
+		cfg := loadConfig()
+		configMu.RUnlock()
+		if !mutationEnabled(cfg, name) {
+			sendError(w, fmt.Sprintf("mutation %q is disabled", name), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// cmdSlots limits concurrent subprocesses; main resizes it from
+// Config.MaxConcurrentCommands.
+var cmdSlots = make(chan struct{}, 8)
+
+// execWrapper is Config.ExecWrapper split into words; main sets it at
+// startup. Empty runs commands directly.
+var execWrapper []string
+
+// proxyEnv is the validated Config.ProxyEnv, set by main. It is added to
+// every bd/gt subprocess environment; empty inherits rigradar's own.
+var proxyEnv map[string]string
+
+// proxyVars are the variables Config.ProxyEnv may set.
+var proxyVars = map[string]bool{
+	"HTTP_PROXY": true, "HTTPS_PROXY": true, "NO_PROXY": true, "ALL_PROXY": true,
+	"http_proxy": true, "https_proxy": true, "no_proxy": true, "all_proxy": true,
+}
+
+// validProxyEnv drops (and logs) ProxyEnv entries that are not proxy
+// variables, so the setting can't be used to override BEADS_DIR or PATH.
+func validProxyEnv(env map[string]string) map[string]string {
+	valid := make(map[string]string, len(env))
+	for k, v := range env {
+		if !proxyVars[k] {
+			log.Printf("Warning: proxyEnv: %s is not a proxy variable, ignoring", k)
+			continue
+		}
+		valid[k] = v
+	}
+	return valid
+}
 
-	if len(env) > 0 {
-		cmd.Env = os.Environ()
-		for k, v := range env {
-			cmd.Env = append(cmd.Env, k+"="+v)
+// wrapCommand returns the argv that runs name with args under wrapper.
+// The word "{cmd}" is replaced by the command and its arguments; without
+// it they are appended. No shell is involved, so nothing is re-parsed.
+func wrapCommand(wrapper []string, name string, args []string) []string {
+	cmdline := append([]string{name}, args...)
+	if len(wrapper) == 0 {
+		return cmdline
+	}
+	var argv []string
+	substituted := false
+	for _, w := range wrapper {
+		if w == "{cmd}" {
+			argv = append(argv, cmdline...)
+			substituted = true
+			continue
 		}
+		argv = append(argv, w)
+	}
+	if !substituted {
+		argv = append(argv, cmdline...)
+	}
+	return argv
+}
+
+// execCmd runs a command that is not tied to any request. Handlers use
+// execCmdContext with r.Context() so a client disconnect kills the
+// subprocess.
+func execCmd(name string, args []string, env map[string]string) (json.RawMessage, error) {
+	return execCmdContext(context.Background(), name, args, env)
+}
+
+// execCmdContext runs a command like execCmd, but waits for a free slot
+// and gives up as soon as ctx is done, whether queued or running.
+func execCmdContext(parent context.Context, name string, args []string, env map[string]string) (json.RawMessage, error) {
+	select {
+	case cmdSlots <- struct{}{}:
+		defer func() { <-cmdSlots }()
+	case <-parent.Done():
+		return nil, fmt.Errorf("%s: %w", describeCommand(name, args, env), parent.Err())
 	}
 
+	ctx, cancel := context.WithTimeout(parent, cmdTimeout)
+	defer cancel()
+
+	argv := wrapCommand(execWrapper, name, args)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = townRoot
+	cmd.Env = commandEnv(env)
+
 	out, err := cmd.Output()
 	if err != nil {
+		// A killed process also reports an ExitError, so check the
+		// contexts first.
+		switch {
+		case parent.Err() != nil:
+			return nil, fmt.Errorf("%s: %w", describeCommand(name, args, env), parent.Err())
+		case ctx.Err() != nil:
+			return nil, fmt.Errorf("%s: timed out after %s: %w", describeCommand(name, args, env), cmdTimeout, ctx.Err())
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("%s %s exited %d: %s", name, strings.Join(args, " "), exitErr.ExitCode(), string(exitErr.Stderr))
+			return nil, &cmdError{
+				Command:  name,
+				Args:     args,
+				ExitCode: exitErr.ExitCode(),
+				Stderr:   string(exitErr.Stderr),
+				desc:     describeCommand(name, args, env),
+			}
 		}
-		return nil, err
+		return nil, fmt.Errorf("%s: %w", describeCommand(name, args, env), err)
 	}
 
 	// Try to parse as JSON; if it fails, wrap as a JSON string
@@ -265,24 +678,145 @@ func execCmd(name string, args []string, env map[string]string) (json.RawMessage
 	return json.RawMessage(quoted), nil
 }
 
+// cmdError is a bd/gt run that exited non-zero. Handlers pass ExitCode on
+// to clients (see sendCmdError) so they can tell failure modes apart.
+type cmdError struct {
+	Command  string
+	Args     []string
+	ExitCode int
+	Stderr   string
+	desc     string // describeCommand, for the message
+}
+
+func (e *cmdError) Error() string {
+	return fmt.Sprintf("%s exited %d: %s", e.desc, e.ExitCode, e.Stderr)
+}
+
+// exitCode returns the exit code of a failed bd/gt run inside err.
+func exitCode(err error) (int, bool) {
+	var ce *cmdError
+	if errors.As(err, &ce) {
+		return ce.ExitCode, true
+	}
+	return 0, false
+}
+
+// cmdTimeout bounds a single bd/gt run.
+var cmdTimeout = 15 * time.Second
+
+// describeCommand renders a bd/gt invocation for error messages: the
+// command line with arguments quoted where needed, then the variables
+// set for it, e.g. `bd show "ri 1" --json (BEADS_DIR=/town/ri/.beads)`.
+// Values of variables that look like secrets are redacted.
+func describeCommand(name string, args []string, env map[string]string) string {
+	parts := []string{name}
+	for _, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n\"'\\$") {
+			a = strconv.Quote(a)
+		}
+		parts = append(parts, a)
+	}
+	line := strings.Join(parts, " ")
+	if len(env) == 0 {
+		return line
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	vars := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vars = append(vars, k+"="+redactEnvValue(k, env[k]))
+	}
+	return line + " (" + strings.Join(vars, " ") + ")"
+}
+
+// redactEnvValue hides values of variables named like credentials and the
+// password in URLs (e.g. proxy settings).
+func redactEnvValue(key, value string) string {
+	upper := strings.ToUpper(key)
+	for _, word := range []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "KEY", "AUTH", "CREDENTIAL"} {
+		if strings.Contains(upper, word) {
+			return "[redacted]"
+		}
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return value
+}
+
+// commandEnv is the environment for a bd/gt subprocess: rigradar's own
+// plus proxyEnv and env, or nil (inherit) when there is nothing to add.
+func commandEnv(env map[string]string) []string {
+	if len(env) == 0 && len(proxyEnv) == 0 {
+		return nil
+	}
+	out := os.Environ()
+	for k, v := range proxyEnv {
+		out = append(out, k+"="+v)
+	}
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	return out
+}
+
 func sendJSON(w http.ResponseWriter, data any, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
 
 func sendError(w http.ResponseWriter, msg string, status int) {
-	sendJSON(w, map[string]string{"error": msg}, status)
+	sendErrorDetails(w, msg, status, nil)
+}
+
+// sendErrorDetails is sendError with extra fields in the error body, for
+// errors the client can act on (e.g. the confirmation a bulk call needs).
+func sendErrorDetails(w http.ResponseWriter, msg string, status int, details map[string]any) {
+	body := map[string]any{"error": msg}
+	for k, v := range details {
+		body[k] = v
+	}
+	if id := w.Header().Get("X-Request-Id"); id != "" {
+		body["requestId"] = id
+	}
+	sendJSON(w, body, status)
+}
+
+// sendCmdError is sendError for a failed bd/gt run, adding "exitCode"
+// when the command exited non-zero.
+func sendCmdError(w http.ResponseWriter, err error, status int) {
+	if code, ok := exitCode(err); ok {
+		sendErrorDetails(w, err.Error(), status, map[string]any{"exitCode": code})
+		return
+	}
+	sendError(w, err.Error(), status)
+}
+
+// handleAPINotFound answers unmatched /api/ paths with a JSON 404 so the
+// UI's fetch error handling sees the same shape as any other API error.
+func handleAPINotFound(w http.ResponseWriter, r *http.Request) {
+	sendErrorDetails(w, "not found", http.StatusNotFound, map[string]any{"code": "NOT_FOUND"})
 }
 
+// corsMiddleware answers preflight requests. When next is the route mux,
+// Access-Control-Allow-Methods lists the methods routed at the requested
+// path; otherwise it falls back to GET and POST.
 func corsMiddleware(next http.Handler) http.Handler {
+	mux, _ := next.(*http.ServeMux)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodOptions {
+			methods := "GET,POST,OPTIONS"
+			if mux != nil {
+				methods = routeMethods(mux, r)
+			}
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", methods)
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -291,153 +825,1468 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func handleIndex(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write(indexHTML)
-}
+// fallbackRoutes match paths no other route claims; a path only they
+// match doesn't really support their method.
+var fallbackRoutes = map[string]bool{"GET /": true, "GET /api/": true, "POST /api/": true}
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	sendJSON(w, map[string]any{
-		"status": "ok",
-		"town":   townRoot,
-		"engine": "go",
-	}, http.StatusOK)
+// routeMethods lists the methods mux routes at r's path, then OPTIONS.
+func routeMethods(mux *http.ServeMux, r *http.Request) string {
+	var methods []string
+	for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		probe := r.Clone(r.Context())
+		probe.Method = m
+		if _, pattern := mux.Handler(probe); pattern != "" && !fallbackRoutes[pattern] {
+			methods = append(methods, m)
+		}
+	}
+	return strings.Join(append(methods, http.MethodOptions), ",")
 }
 
-func handleReady(w http.ResponseWriter, r *http.Request) {
-	data, err := execCmd("gt", []string{"ready", "--json"}, nil)
-	if err != nil {
-		sendError(w, err.Error(), http.StatusInternalServerError)
+func handleIndex(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+
+	if r.URL.Path != "/" && cfg.PlainNotFound {
+		http.NotFound(w, r)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Write(data)
+	serveIndex(w, r, cfg, map[string]any{})
 }
 
-func handleStatus(w http.ResponseWriter, r *http.Request) {
-	data, err := execCmd("gt", []string{"status", "--json"}, nil)
-	if err != nil {
-		sendError(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+// handleBeadPermalink serves the dashboard for GET /b/{id} with a bootstrap
+// hint that opens the bead on load. A malformed id gets the plain
+// dashboard.
+func handleBeadPermalink(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
 
-	// Enrich with rig prefix mapping for frontend display
-	var statusObj map[string]json.RawMessage
-	if json.Unmarshal(data, &statusObj) == nil {
-		prefixes := buildRigPrefixNameMap()
-		prefixJSON, _ := json.Marshal(prefixes)
-		statusObj["rigPrefixes"] = json.RawMessage(prefixJSON)
-		enriched, _ := json.Marshal(statusObj)
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Write(enriched)
-		return
+	boot := map[string]any{}
+	if id := r.PathValue("id"); validBeadID(id) {
+		boot["openBead"] = id
 	}
+	serveIndex(w, r, cfg, boot)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Write(data)
+// validBeadID reports whether id looks like a bead id: letters, digits,
+// '-', '_' and '.', starting with a letter or digit. Mutations check ids
+// with it before passing them to bd as positional arguments, where a
+// leading '-' would be taken for a flag.
+func validBeadID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case i > 0 && (c == '-' || c == '_' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
 }
 
-func handleBeads(w http.ResponseWriter, r *http.Request) {
-	status := r.URL.Query().Get("status")
-	btype := r.URL.Query().Get("type")
+// handleValidateID checks a bead id without running bd: it must be
+// well-formed, "prefix-rest", and its prefix must resolve to a beads
+// directory. The answer is always 200; reason says what is wrong.
+func handleValidateID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	result := map[string]any{"valid": false, "prefix": nil, "resolvedDir": nil}
+	prefix, dir, ok := resolveBeadPrefix(id)
+	switch {
+	case !validBeadID(id) || prefix == "" || strings.HasSuffix(id, "-"):
+		result["reason"] = "malformed id: want prefix-id, e.g. ri-abc"
+	case !ok:
+		result["prefix"] = prefix
+		result["reason"] = fmt.Sprintf("unknown prefix %q", prefix)
+	default:
+		result["valid"] = true
+		result["prefix"] = prefix
+		result["resolvedDir"] = dir
+	}
+	sendJSON(w, result, http.StatusOK)
+}
 
-	// Collect unique bead dirs
-	dirs := make(map[string]bool)
-	for _, d := range prefixMap {
-		dirs[d] = true
+// serveIndex writes the index page with boot, plus the settings every page
+// load carries, injected as window.RIGRADAR_BOOTSTRAP.
+func serveIndex(w http.ResponseWriter, r *http.Request, cfg Config, boot map[string]any) {
+	if cfg.InlineConfig {
+		boot["config"] = cfg
+	}
+	if missing := missingBinaries(); len(missing) > 0 {
+		boot["missingBinaries"] = missing
 	}
 
-	type result struct {
-		data json.RawMessage
-		err  error
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Vary", "Accept-Encoding")
+	// The precomputed gzip and ETag only match the page as embedded; pages
+	// with a bootstrap or banner injected go out uncompressed and are
+	// tagged by their own content.
+	if len(boot) == 0 && cfg.BannerMessage == "" && !themed(cfg) {
+		gz := acceptsGzip(r)
+		etag := indexETag()
+		if gz {
+			etag = strings.TrimSuffix(etag, `"`) + `-gz"`
+		}
+		if notModified(w, r, etag) {
+			return
+		}
+		if gz {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(indexGzip())
+			return
+		}
+		w.Write(indexHTML)
+		return
+	}
+	page := injectTheme(injectBanner(injectBootstrap(indexHTML, boot), cfg), cfg)
+	if notModified(w, r, contentETag(page)) {
+		return
 	}
+	w.Write(page)
+}
 
-	ch := make(chan result, len(dirs))
-	for dir := range dirs {
-		go func(d string) {
-			args := []string{"list", "--json"}
-			if status != "" {
-				args = append(args, "--status="+status)
-			}
-			if btype != "" {
-				args = append(args, "--type="+btype)
-			}
-			data, err := execCmd("bd", args, map[string]string{"BEADS_DIR": d})
-			ch <- result{data, err}
-		}(dir)
+// indexETag tags the embedded index page. It only changes when rigradar
+// is rebuilt.
+var indexETag = sync.OnceValue(func() string { return contentETag(indexHTML) })
+
+func contentETag(b []byte) string {
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// notModified sets the ETag header and answers 304 when the request's
+// If-None-Match already names it.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
 	}
+	return false
+}
 
-	var allBeads []json.RawMessage
-	for range dirs {
-		res := <-ch
-		if res.err != nil {
+// indexGzip is the embedded index page, gzip-compressed once on first use.
+var indexGzip = sync.OnceValue(func() []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(indexHTML)
+	zw.Close()
+	return buf.Bytes()
+})
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if coding != "gzip" && coding != "*" {
 			continue
 		}
-		var arr []json.RawMessage
-		if json.Unmarshal(res.data, &arr) == nil {
-			allBeads = append(allBeads, arr...)
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			return err == nil && v > 0
 		}
+		return true
 	}
+	return false
+}
 
-	if allBeads == nil {
-		allBeads = []json.RawMessage{}
+// injectBanner renders Config.BannerMessage (HTML-escaped) right after
+// <body>, so it shows even before the UI script runs.
+func injectBanner(page []byte, cfg Config) []byte {
+	if cfg.BannerMessage == "" {
+		return page
+	}
+	level := "info"
+	if cfg.BannerLevel == "warn" {
+		level = "warn"
 	}
-	sendJSON(w, allBeads, http.StatusOK)
+	banner := fmt.Sprintf(`<body>
+<div class="banner banner-%s" id="banner">%s</div>`, level, html.EscapeString(cfg.BannerMessage))
+	return []byte(strings.Replace(string(page), "<body>", banner, 1))
 }
 
-func handleBeadDetail(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/api/bead/")
-	if id == "" {
-		sendError(w, "missing bead id", http.StatusBadRequest)
-		return
+// themed reports whether the page needs a data-theme attribute; the
+// embedded page is already dark.
+func themed(cfg Config) bool {
+	return cfg.Theme == "light" || cfg.Theme == "auto"
+}
+
+// injectTheme sets data-theme on the page's <html> element so its CSS
+// picks the configured color scheme.
+func injectTheme(page []byte, cfg Config) []byte {
+	if !themed(cfg) {
+		return page
 	}
+	return []byte(strings.Replace(string(page), "<html ", `<html data-theme="`+cfg.Theme+`" `, 1))
+}
 
-	dir := beadsDirForID(id)
-	data, err := execCmd("bd", []string{"show", id, "--json"}, map[string]string{"BEADS_DIR": dir})
+// injectBootstrap adds window.RIGRADAR_BOOTSTRAP to the page head. The
+// page is returned untouched when there is nothing to inject.
+// json.Marshal escapes <, > and &, so the payload cannot close the
+// script tag early.
+func injectBootstrap(page []byte, boot map[string]any) []byte {
+	if len(boot) == 0 {
+		return page
+	}
+	data, err := json.Marshal(boot)
 	if err != nil {
-		sendError(w, err.Error(), http.StatusInternalServerError)
-		return
+		return page
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Write(data)
+	script := "<script>window.RIGRADAR_BOOTSTRAP = " + string(data) + ";</script>\n</head>"
+	return []byte(strings.Replace(string(page), "</head>", script, 1))
 }
 
-func handleGetConfig(w http.ResponseWriter, r *http.Request) {
-	configMu.RLock()
-	cfg := loadConfig()
-	configMu.RUnlock()
-	sendJSON(w, cfg, http.StatusOK)
+// staticHandler serves the embedded static/ tree. Assets only change when
+// rigradar is rebuilt, so they can be cached for a while.
+func staticHandler() http.Handler {
+	sub, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix("/static/", http.FileServerFS(sub))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		files.ServeHTTP(w, r)
+	})
 }
 
-func handlePostConfig(w http.ResponseWriter, r *http.Request) {
-	var body Config
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		sendError(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+// handleHealth answers from memory only, without running bd or gt, so
+// liveness probes and the startup instance check stay fast. Versions are
+// in /health/deep.
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, map[string]any{
+		"status":   "ok",
+		"town":     townRoot,
+		"townName": townName(),
+		"engine":   "go",
+		"schema":   currentSchema(),
+	}, http.StatusOK)
+}
 
-	configMu.Lock()
-	current := loadConfig()
-	// Merge: body overwrites current where set
-	if body.Server.Port != 0 {
-		current.Server.Port = body.Server.Port
+// handleHealthDeep checks the town root and that bd and gt run, using the
+// cached tool versions so frequent probes stay cheap. 503 when unhealthy.
+func handleHealthDeep(w http.ResponseWriter, r *http.Request) {
+	versions := toolVersions()
+	var problems []string
+	if info, err := os.Stat(townRoot); err != nil || !info.IsDir() {
+		problems = append(problems, "town root not found: "+townRoot)
 	}
-	if body.Server.Host != "" {
-		current.Server.Host = body.Server.Host
+	for _, tool := range versionedTools {
+		if v := versions[tool]; !v.Available {
+			problems = append(problems, fmt.Sprintf("%s unavailable: %s", tool, v.Error))
+		}
 	}
-	if body.RefreshInterval != 0 {
-		current.RefreshInterval = body.RefreshInterval
+
+	status, code := "ok", http.StatusOK
+	if len(problems) > 0 {
+		status, code = "degraded", http.StatusServiceUnavailable
 	}
-	// Filters: always overwrite from body since bools default to false
-	current.Filters = body.Filters
-	saveConfig(current)
-	configMu.Unlock()
+	sendJSON(w, map[string]any{
+		"status":   status,
+		"town":     townRoot,
+		"versions": versions,
+		"problems": problems,
+	}, code)
+}
 
-	sendJSON(w, current, http.StatusOK)
+// handleLivez reports only that the process is up and serving.
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, map[string]string{"status": "alive"}, http.StatusOK)
+}
+
+// binaryCheckTTL is how long a missing/present verdict for bd and gt is
+// reused by the index page.
+const binaryCheckTTL = time.Minute
+
+var binaryCache struct {
+	mu      sync.Mutex
+	missing []string
+	checked time.Time
+}
+
+// missingBinaries returns which of bd and gt are not on PATH, so the UI
+// can explain the problem instead of showing failed fetches.
+func missingBinaries() []string {
+	binaryCache.mu.Lock()
+	defer binaryCache.mu.Unlock()
+	if !binaryCache.checked.IsZero() && time.Since(binaryCache.checked) < binaryCheckTTL {
+		return binaryCache.missing
+	}
+	var missing []string
+	for _, bin := range []string{"bd", "gt"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			missing = append(missing, bin)
+		}
+	}
+	binaryCache.missing = missing
+	binaryCache.checked = time.Now()
+	return missing
+}
+
+// handleReadyz reports whether rigradar can actually serve bead data:
+// the town root must exist and the bd binary must resolve.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if draining.Load() {
+		sendJSON(w, map[string]any{"status": "draining", "inFlight": otherInFlight()}, http.StatusServiceUnavailable)
+		return
+	}
+	var reasons []string
+	if info, err := os.Stat(townRoot); err != nil || !info.IsDir() {
+		reasons = append(reasons, "town root not found: "+townRoot)
+	}
+	if _, err := exec.LookPath("bd"); err != nil {
+		reasons = append(reasons, "bd binary not found in PATH")
+	}
+
+	if len(reasons) > 0 {
+		sendJSON(w, map[string]any{"status": "not ready", "reasons": reasons}, http.StatusServiceUnavailable)
+		return
+	}
+	sendJSON(w, map[string]string{"status": "ready"}, http.StatusOK)
+}
+
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	data, err := execCmdContext(r.Context(), "gt", []string{"ready", "--json"}, nil)
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if polecat := r.URL.Query().Get("polecat"); polecat != "" {
+		sendJSON(w, readyFor(data, polecat), http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// readyFor narrows `gt ready --json` output to the beads polecat can pick
+// up: those assigned to it and unassigned ones. gt has no per-polecat
+// filter, so this is done here. Assignees may be a bare name or a
+// rig/polecats/name path. Each bead gains a "rig" name from the prefix
+// map. Output that is not a bead list (e.g. a "nothing ready" message)
+// counts as empty.
+func readyFor(data json.RawMessage, polecat string) []map[string]any {
+	out := []map[string]any{}
+	beads, err := decodeBeadList(data)
+	if err != nil {
+		return out
+	}
+	rigNames := buildRigPrefixNameMap()
+	for _, raw := range beads {
+		var bead map[string]any
+		if json.Unmarshal(raw, &bead) != nil {
+			continue
+		}
+		assignee, _ := bead["assignee"].(string)
+		if assignee != "" && assignee != polecat && !strings.HasSuffix(assignee, "/"+polecat) {
+			continue
+		}
+		if _, ok := bead["rig"]; !ok {
+			id, _ := bead["id"].(string)
+			bead["rig"] = beadRig(id, rigNames)
+		}
+		out = append(out, bead)
+	}
+	return out
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	data, err := execCmdContext(r.Context(), "gt", []string{"status", "--json"}, nil)
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Enrich with rig prefix mapping for frontend display
+	var statusObj map[string]json.RawMessage
+	if json.Unmarshal(data, &statusObj) == nil {
+		prefixes := buildRigPrefixNameMap()
+		prefixJSON, _ := json.Marshal(prefixes)
+		statusObj["rigPrefixes"] = json.RawMessage(prefixJSON)
+		if _, ok := statusObj["townName"]; !ok {
+			nameJSON, _ := json.Marshal(townName())
+			statusObj["townName"] = json.RawMessage(nameJSON)
+		}
+		enriched, _ := json.Marshal(statusObj)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Write(enriched)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// bdList runs `bd list --json` against one beads directory with extra
+// filter args and returns the beads it reports. With directRead it reads
+// beads.db first and only runs bd if that fails.
+func bdList(ctx context.Context, dir string, args []string) ([]json.RawMessage, error) {
+	if directRead {
+		if beads, err := directList(ctx, dir, args); err == nil {
+			return beads, nil
+		}
+	}
+	data, err := execCmdContext(ctx, "bd", append([]string{"list", "--json"}, args...), map[string]string{"BEADS_DIR": dir})
+	if err != nil {
+		return nil, err
+	}
+	return decodeBeadList(data)
+}
+
+// listWrapperKeys are the keys decodeBeadList looks under for a wrapped
+// bead array. main replaces them with Config.ListWrapperKeys when set.
+var listWrapperKeys = []string{"beads", "items", "data", "results"}
+
+// decodeBeadList accepts both a bare array of beads and an object that
+// wraps the array under one of listWrapperKeys (one level of nesting,
+// e.g. {"data": {"items": [...]}}, included), since bd has emitted several
+// shapes across versions.
+func decodeBeadList(data json.RawMessage) ([]json.RawMessage, error) {
+	if beads, ok := unwrapBeadList(data, 2); ok {
+		return beads, nil
+	}
+	return nil, fmt.Errorf("unexpected bd list output: %s", truncateOutput(data))
+}
+
+func unwrapBeadList(data json.RawMessage, depth int) ([]json.RawMessage, bool) {
+	var beads []json.RawMessage
+	if json.Unmarshal(data, &beads) == nil {
+		return beads, true
+	}
+	var obj map[string]json.RawMessage
+	if depth == 0 || json.Unmarshal(data, &obj) != nil {
+		return nil, false
+	}
+	for _, key := range listWrapperKeys {
+		if inner, ok := obj[key]; ok {
+			if beads, ok := unwrapBeadList(inner, depth-1); ok {
+				return beads, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func truncateOutput(data []byte) string {
+	const max = 200
+	if len(data) <= max {
+		return string(data)
+	}
+	return string(data[:max]) + "..."
+}
+
+// beadDirs returns the unique beads directories behind prefixMap, sorted
+// so fan-outs visit rigs in a stable order.
+func beadDirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, d := range currentPrefixMap() {
+		if !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// dirExplanation is one queried directory in /api/beads?explain=1.
+type dirExplanation struct {
+	Dir      string   `json:"dir"`
+	Prefixes []string `json:"prefixes"`
+}
+
+// explainDirs reports the directories a fan-out queries and every
+// prefix-map key that resolves to each.
+func explainDirs(dirs []string, truncated bool) map[string]any {
+	keys := make(map[string][]string)
+	for key, dir := range currentPrefixMap() {
+		keys[dir] = append(keys[dir], key)
+	}
+	out := make([]dirExplanation, 0, len(dirs))
+	for _, dir := range dirs {
+		prefixes := keys[dir]
+		sort.Strings(prefixes)
+		out = append(out, dirExplanation{Dir: dir, Prefixes: prefixes})
+	}
+	return map[string]any{"dirs": out, "truncated": truncated}
+}
+
+// globDirs keeps the dirs that a prefix-map key (prefix or rig name)
+// matching the path.Match pattern resolves to. The pattern must be valid.
+func globDirs(dirs []string, pattern string) []string {
+	match := make(map[string]bool)
+	for key, dir := range currentPrefixMap() {
+		if ok, _ := path.Match(pattern, key); ok {
+			match[dir] = true
+		}
+	}
+	var out []string
+	for _, dir := range dirs {
+		if match[dir] {
+			out = append(out, dir)
+		}
+	}
+	return out
+}
+
+// limitDirs keeps the first max dirs (beadDirs is sorted, so the choice
+// is stable) and reports whether any were dropped. max <= 0 is unlimited.
+func limitDirs(dirs []string, max int) ([]string, bool) {
+	if max <= 0 || len(dirs) <= max {
+		return dirs, false
+	}
+	return dirs[:max], true
+}
+
+// withBeadsBudget bounds a bead fan-out by Config.BeadsBudgetMs.
+func withBeadsBudget(parent context.Context) (context.Context, context.CancelFunc) {
+	configMu.RLock()
+	budget := time.Duration(loadConfig().BeadsBudgetMs) * time.Millisecond
+	configMu.RUnlock()
+	if budget <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, budget)
+}
+
+// dirBeads is one directory's answer in a `bd list` fan-out.
+type dirBeads struct {
+	dir   string
+	beads []json.RawMessage
+	err   error
+}
+
+// fanOutBeads runs `bd list` with args in every dir concurrently and calls
+// emit with each dir's answer as it arrives. It returns the number of dirs
+// that had not answered when ctx ended.
+func fanOutBeads(ctx context.Context, dirs []string, args []string, emit func(dirBeads)) (outstanding int) {
+	ch := make(chan dirBeads, len(dirs))
+	for _, dir := range dirs {
+		go func(d string) {
+			beads, err := bdList(ctx, d, args)
+			ch <- dirBeads{d, beads, err}
+		}(dir)
+	}
+
+	for answered := range len(dirs) {
+		select {
+		case res := <-ch:
+			emit(res)
+		case <-ctx.Done():
+			return len(dirs) - answered
+		}
+	}
+	return 0
+}
+
+// collectBeads merges a fanOutBeads run. Failing dirs are reported in
+// errs. If ctx ends before every dir has answered, the beads gathered so
+// far are returned with partial set.
+func collectBeads(ctx context.Context, dirs []string, args []string) (beads []json.RawMessage, errs []dirError, partial bool) {
+	beads = []json.RawMessage{}
+	errs = []dirError{}
+	outstanding := fanOutBeads(ctx, dirs, args, func(res dirBeads) {
+		if res.err != nil {
+			errs = append(errs, dirError{Dir: res.dir, Error: res.err.Error()})
+			return
+		}
+		beads = append(beads, res.beads...)
+	})
+	if outstanding > 0 {
+		partial = true
+		log.Printf("beads: fan-out deadline reached with %d rigs outstanding, returning partial results", outstanding)
+	}
+	return beads, errs, partial
+}
+
+func handleBeads(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+
+	dirs := beadDirs()
+	if glob := r.URL.Query().Get("rigGlob"); glob != "" {
+		if _, err := path.Match(glob, ""); err != nil {
+			sendError(w, fmt.Sprintf("invalid rigGlob %q: %v", glob, err), http.StatusBadRequest)
+			return
+		}
+		dirs = globDirs(dirs, glob)
+	}
+	dirs, truncated := limitDirs(dirs, cfg.MaxRigs)
+	if r.URL.Query().Get("explain") == "1" {
+		sendJSON(w, explainDirs(dirs, truncated), http.StatusOK)
+		return
+	}
+
+	if warming.Load() && !cfg.WarmupServeCold {
+		retry := cfg.WarmupRetryAfterSec
+		if retry <= 0 {
+			retry = 5
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		sendError(w, "warming up, retry shortly", http.StatusServiceUnavailable)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	// bd takes a single --type; several types are fetched unfiltered and
+	// narrowed down after merging.
+	types := splitList(r.URL.Query().Get("type"))
+	// bd has no "unassigned" filter, so assignee=none is applied after
+	// merging; a missing and an empty assignee both count.
+	assignee := r.URL.Query().Get("assignee")
+	unassigned := assignee == "none" || r.URL.Query().Get("unassigned") == "1"
+
+	var args []string
+	if status != "" {
+		args = append(args, "--status="+status)
+	}
+	if len(types) == 1 {
+		args = append(args, "--type="+types[0])
+	}
+	if assignee != "" && !unassigned {
+		args = append(args, "--assignee="+assignee)
+	}
+	dateArgs, timeFilters, err := dateRangeArgs(r.URL.Query().Get, time.Now())
+	if err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	args = append(args, dateArgs...)
+	if r.URL.Query().Get("archived") == "1" {
+		if !bdListSupports("--archived") {
+			sendError(w, "this bd version does not support listing archived beads", http.StatusNotImplemented)
+			return
+		}
+		args = append(args, "--archived")
+	}
+
+	view := r.URL.Query().Get("view")
+	switch view {
+	case "", "full", "sidebar":
+	default:
+		sendError(w, fmt.Sprintf("unknown view %q", view), http.StatusBadRequest)
+		return
+	}
+	var dims []string
+	if groupBy := r.URL.Query().Get("groupBy"); groupBy != "" {
+		if view == "sidebar" {
+			sendError(w, "view=sidebar cannot be combined with groupBy", http.StatusBadRequest)
+			return
+		}
+		dims = strings.Split(groupBy, ",")
+		for i, d := range dims {
+			dims[i] = strings.TrimSpace(d)
+			if !groupDimensions[dims[i]] {
+				sendError(w, fmt.Sprintf("unknown groupBy dimension %q", dims[i]), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	ctx, cancel := withBeadsBudget(r.Context())
+	defer cancel()
+
+	if truncated {
+		log.Printf("Warning: %d beads directories exceed maxRigs=%d; only the first %d are queried", len(beadDirs()), cfg.MaxRigs, cfg.MaxRigs)
+		w.Header().Set("X-Rigradar-Truncated", "true")
+	}
+	allBeads, errs, partial := fetchBeads(ctx, dirs, args, beadsTTL(cfg))
+	allBeads = filterByTime(allBeads, timeFilters)
+	if len(types) > 1 {
+		allBeads = filterByField(allBeads, "issue_type", types)
+	}
+	if unassigned {
+		allBeads = filterByField(allBeads, "assignee", []string{""})
+	}
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		allBeads = filterByLabel(allBeads, tag)
+	}
+	var warnings []string
+	if q := r.URL.Query().Get("q"); q != "" {
+		fields, unknown := textFields(r.URL.Query().Get("qField"))
+		if len(unknown) > 0 {
+			warnings = append(warnings, fmt.Sprintf("unknown qField %s; valid fields are id, title, description (or body)", strings.Join(unknown, ", ")))
+			allBeads = []json.RawMessage{}
+		} else {
+			allBeads = filterByText(allBeads, q, fields)
+		}
+	}
+	// An explicit status wins over includeClosed.
+	if status == "" && r.URL.Query().Get("includeClosed") == "0" {
+		allBeads = rejectByField(allBeads, "status", []string{"closed"})
+	}
+	if s := r.URL.Query().Get("titleMax"); s != "" {
+		max, err := strconv.Atoi(s)
+		if err != nil || max < 1 {
+			sendError(w, fmt.Sprintf("invalid titleMax %q: want a positive number", s), http.StatusBadRequest)
+			return
+		}
+		allBeads = truncateTitles(allBeads, max)
+	}
+	if partial {
+		// Beads missing from a partial result would look removed, so it
+		// gets no cursor.
+		w.Header().Set("X-Rigradar-Partial", "true")
+	} else if changed, ok := applyCursor(w, r, allBeads); ok {
+		allBeads = changed
+	} else {
+		return
+	}
+	verbose := r.URL.Query().Get("verbose") == "1"
+
+	if view == "sidebar" {
+		sendBeads(w, sidebarBeads(allBeads), errs, warnings, verbose)
+		return
+	}
+	if dims != nil {
+		groups := groupBeads(allBeads, dims)
+		groups.remapFields(cfg.ResponseFieldNames)
+		sendBeads(w, groups, errs, warnings, verbose)
+		return
+	}
+	sendBeads(w, remapBeads(allBeads, cfg.ResponseFieldNames), errs, warnings, verbose)
+}
+
+// dirError records a beads directory whose bd call failed during a fan-out.
+type dirError struct {
+	Dir   string `json:"dir"`
+	Error string `json:"error"`
+}
+
+// sendBeads writes a /api/beads result. Per-directory failures are dropped
+// from the normal response; with ?verbose=1 the result is wrapped as
+// {"beads": ..., "errors": [...]} so they can be inspected.
+func sendBeads(w http.ResponseWriter, result any, errs []dirError, warnings []string, verbose bool) {
+	if verbose {
+		env := map[string]any{"beads": result, "errors": errs}
+		if len(warnings) > 0 {
+			env["warnings"] = warnings
+		}
+		sendJSON(w, env, http.StatusOK)
+		return
+	}
+	sendJSON(w, result, http.StatusOK)
+}
+
+// groupDimensions are the bead fields accepted by ?groupBy=.
+var groupDimensions = map[string]bool{
+	"status":   true,
+	"priority": true,
+	"type":     true,
+	"rig":      true,
+	"assignee": true,
+}
+
+// beadGroup is one level of a ?groupBy= response. Inner levels carry
+// nested groups; the innermost level carries the beads themselves.
+type beadGroup struct {
+	Count  int                   `json:"count"`
+	Groups map[string]*beadGroup `json:"groups,omitempty"`
+	Beads  []json.RawMessage     `json:"beads,omitempty"`
+}
+
+func groupBeads(beads []json.RawMessage, dims []string) *beadGroup {
+	root := &beadGroup{}
+	for _, raw := range beads {
+		var bead map[string]any
+		if json.Unmarshal(raw, &bead) != nil {
+			continue
+		}
+		g := root
+		g.Count++
+		for _, dim := range dims {
+			if g.Groups == nil {
+				g.Groups = make(map[string]*beadGroup)
+			}
+			key := groupKey(bead, dim)
+			child, ok := g.Groups[key]
+			if !ok {
+				child = &beadGroup{}
+				g.Groups[key] = child
+			}
+			child.Count++
+			g = child
+		}
+		g.Beads = append(g.Beads, raw)
+	}
+	return root
+}
+
+// remapFields applies Config.ResponseFieldNames to the beads at the leaves.
+func (g *beadGroup) remapFields(names map[string]string) {
+	g.Beads = remapBeads(g.Beads, names)
+	for _, sub := range g.Groups {
+		sub.remapFields(names)
+	}
+}
+
+// groupKey returns the bucket a bead falls into along one grouping dimension.
+func groupKey(bead map[string]any, dim string) string {
+	switch dim {
+	case "status":
+		if s, ok := bead["status"].(string); ok && s != "" {
+			return s
+		}
+	case "priority":
+		if p, ok := beadPriority(bead["priority"]); ok {
+			return strconv.Itoa(p)
+		}
+	case "type":
+		if t, ok := bead["issue_type"].(string); ok && t != "" {
+			return t
+		}
+	case "rig":
+		if id, ok := bead["id"].(string); ok {
+			if dash := strings.Index(id, "-"); dash > 0 {
+				return id[:dash]
+			}
+		}
+	case "assignee":
+		// Missing and empty both count as nobody, as with assignee=none.
+		if a, ok := bead["assignee"].(string); ok && strings.TrimSpace(a) != "" {
+			return a
+		}
+		return "unassigned"
+	}
+	return "unknown"
+}
+
+// sidebarBead is the ?view=sidebar projection of a bead: just what the
+// sidebar renders, with a fixed shape.
+type sidebarBead struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	FullTitle string `json:"fullTitle,omitempty"`
+	Status    string `json:"status"`
+	Priority  *int   `json:"priority"`
+	Rig       string `json:"rig"`
+}
+
+func sidebarBeads(beads []json.RawMessage) []sidebarBead {
+	rigNames := buildRigPrefixNameMap()
+	out := make([]sidebarBead, 0, len(beads))
+	for _, raw := range beads {
+		var bead struct {
+			ID        string `json:"id"`
+			Title     string `json:"title"`
+			FullTitle string `json:"fullTitle"`
+			Status    string `json:"status"`
+			Priority  any    `json:"priority"`
+		}
+		if json.Unmarshal(raw, &bead) != nil {
+			continue
+		}
+		sb := sidebarBead{ID: bead.ID, Title: bead.Title, FullTitle: bead.FullTitle, Status: bead.Status, Rig: beadRig(bead.ID, rigNames)}
+		if p, ok := beadPriority(bead.Priority); ok {
+			sb.Priority = &p
+		}
+		out = append(out, sb)
+	}
+	return out
+}
+
+// beadRig names the rig a bead belongs to from its id prefix, the same
+// way the UI does: hq is the town, known prefixes map to their rig, and
+// anything else falls back to the bare prefix.
+func beadRig(id string, rigNames map[string]string) string {
+	dash := strings.Index(id, "-")
+	if dash <= 0 {
+		return "unknown"
+	}
+	prefix := id[:dash]
+	if prefix == "hq" {
+		return "town"
+	}
+	if name, ok := rigNames[prefix]; ok {
+		return name
+	}
+	return prefix
+}
+
+// loadBead returns `bd show id --json` output, from beads.db with
+// directRead. An id whose prefix maps to no rig is looked for in every
+// beads dir.
+func loadBead(r *http.Request, id string) (json.RawMessage, error) {
+	dir, resolved := lookupBeadsDir(id)
+	var data json.RawMessage
+	var err error
+	if directRead {
+		data, err = directShow(r.Context(), dir, id)
+	}
+	if !directRead || err != nil {
+		data, err = execCmdContext(r.Context(), "bd", []string{"show", id, "--json"}, map[string]string{"BEADS_DIR": dir})
+	}
+	if err != nil && !resolved {
+		// Unknown prefix (e.g. a renamed rig): look for the bead everywhere.
+		ctx, cancel := withBeadsBudget(r.Context())
+		if found, ok := showInAnyDir(ctx, id, beadDirs(), dir); ok {
+			data, err = found, nil
+		}
+		cancel()
+	}
+	return data, err
+}
+
+func handleBeadDetail(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/bead/")
+	if id == "" {
+		sendError(w, "missing bead id", http.StatusBadRequest)
+		return
+	}
+	if base, ok := strings.CutSuffix(id, ".md"); ok {
+		handleBeadMarkdown(w, r, base)
+		return
+	}
+	view := r.URL.Query().Get("view")
+	if view != "" && view != "full" && view != "detail" {
+		sendError(w, fmt.Sprintf("unknown view %q", view), http.StatusBadRequest)
+		return
+	}
+
+	renderBody := r.URL.Query().Get("renderBody") == "1"
+
+	data, err := loadBead(r, id)
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if view == "detail" {
+		detail, err := detailView(data)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if renderBody {
+			detail.BodyHTML = renderMarkdown(detail.Body)
+		}
+		sendJSON(w, detail, http.StatusOK)
+		return
+	}
+	if renderBody {
+		data = withBodyHTML(data)
+	}
+	configMu.RLock()
+	names := loadConfig().ResponseFieldNames
+	configMu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(remapFields(data, names))
+}
+
+// beadDetail is the ?view=detail projection of `bd show`: exactly the
+// fields the detail panel renders, under stable names.
+type beadDetail struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Status   string   `json:"status"`
+	Type     string   `json:"type"`
+	Priority *int     `json:"priority"`
+	Assignee string   `json:"assignee"`
+	Body     string   `json:"body"`
+	Created  string   `json:"created"`
+	Updated  string   `json:"updated"`
+	Blockers []string `json:"blockers"`
+	// BodyHTML is Body rendered by renderMarkdown, with ?renderBody=1.
+	BodyHTML string `json:"bodyHtml,omitempty"`
+}
+
+func detailView(data json.RawMessage) (beadDetail, error) {
+	var list []map[string]any
+	var bead map[string]any
+	if json.Unmarshal(data, &list) == nil && len(list) > 0 {
+		bead = list[0]
+	} else if err := json.Unmarshal(data, &bead); err != nil {
+		return beadDetail{}, fmt.Errorf("unexpected bd show output: %v", err)
+	}
+
+	str := func(key string) string {
+		s, _ := bead[key].(string)
+		return s
+	}
+	d := beadDetail{
+		ID:       str("id"),
+		Title:    str("title"),
+		Status:   str("status"),
+		Type:     str("issue_type"),
+		Assignee: str("assignee"),
+		Body:     str("description"),
+		Created:  str("created_at"),
+		Updated:  str("updated_at"),
+		Blockers: blockerIDs(bead),
+	}
+	if p, ok := beadPriority(bead["priority"]); ok {
+		d.Priority = &p
+	}
+	if d.Blockers == nil {
+		d.Blockers = []string{}
+	}
+	return d, nil
+}
+
+// showInAnyDir runs `bd show id` in every dir except skip concurrently and
+// returns the first successful output. The remaining calls are cancelled.
+func showInAnyDir(ctx context.Context, id string, dirs []string, skip string) (json.RawMessage, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan json.RawMessage)
+	var wg sync.WaitGroup
+	for _, dir := range dirs {
+		if dir == skip {
+			continue
+		}
+		wg.Add(1)
+		go func(dir string) {
+			defer wg.Done()
+			data, err := execCmdContext(ctx, "bd", []string{"show", id, "--json"}, map[string]string{"BEADS_DIR": dir})
+			if err != nil {
+				return
+			}
+			select {
+			case results <- data:
+			case <-ctx.Done():
+			}
+		}(dir)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	data, ok := <-results
+	return data, ok
+}
+
+// rigBeadsDir resolves a rig given as a prefix or rig name ("" means
+// Config.DefaultRig, "town" the town itself) to its beads directory.
+func rigBeadsDir(rig string) (string, bool) {
+	if rig == "" {
+		configMu.RLock()
+		rig = defaultRig(loadConfig())
+		configMu.RUnlock()
+	}
+	if rig == "town" {
+		rig = "hq"
+	}
+	dir, ok := currentPrefixMap()[strings.TrimSuffix(rig, "-")]
+	return dir, ok
+}
+
+// handleBdProxy runs an allowlisted read-only bd subcommand with --json in
+// the rig named by ?rig= (a prefix or rig name; default Config.DefaultRig) and
+// returns its output unchanged.
+func handleBdProxy(w http.ResponseWriter, r *http.Request) {
+	sub := r.PathValue("subcommand")
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	allowed := false
+	for _, c := range cfg.BdReadCommands {
+		if c == sub {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		sendError(w, fmt.Sprintf("bd %s is not in bdReadCommands", sub), http.StatusForbidden)
+		return
+	}
+
+	rig := r.URL.Query().Get("rig")
+	dir, ok := rigBeadsDir(rig)
+	if !ok {
+		sendError(w, fmt.Sprintf("unknown rig %q", rig), http.StatusNotFound)
+		return
+	}
+
+	data, err := execCmdContext(r.Context(), "bd", []string{sub, "--json"}, map[string]string{"BEADS_DIR": dir})
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+func handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+
+	sendJSON(w, map[string]any{
+		"town":           townRoot,
+		"configPath":     configPath,
+		"prefixMap":      currentPrefixMap(),
+		"disabledRoutes": disabledRoutes(),
+		"watch":          watchState,
+		"versions":       toolVersions(),
+		"readOnly":       cfg.ReadOnly,
+		"mutations":      enabledMutations(cfg),
+		"defaultRig":     defaultRig(cfg),
+		"schema":         currentSchema(),
+	}, http.StatusOK)
+}
+
+// rigOverview is one entry of /api/overview and /api/rigs: a beads
+// directory together with its routes, on-disk state, open bead count and
+// (overview only) gt status.
+type rigOverview struct {
+	Name     string   `json:"name"`
+	Path     string   `json:"path"`
+	Prefixes []string `json:"prefixes"`
+	BeadsDir string   `json:"beadsDir"`
+	DBExists bool     `json:"dbExists"`
+	// LastModified is beads.db's mtime, null when there is no db.
+	LastModified *time.Time      `json:"lastModified"`
+	OpenCount    int             `json:"openCount"`
+	Status       json.RawMessage `json:"status,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	// Health is only filled in by /api/rigs (see probeRig).
+	Health *rigHealth `json:"health,omitempty"`
+	// Placeholder marks a Config.AlwaysShowRigs entry no beads directory
+	// was found for.
+	Placeholder bool `json:"placeholder,omitempty"`
+}
+
+// alwaysShownPrefixes is Config.AlwaysShowRigs without the prefixes of
+// disabled routes: a disabled route stays hidden even when listed.
+func alwaysShownPrefixes(cfg Config) []string {
+	disabled := make(map[string]bool)
+	for _, rt := range disabledRoutes() {
+		disabled[strings.TrimSuffix(rt.Prefix, "-")] = true
+	}
+	var out []string
+	for _, p := range cfg.AlwaysShowRigs {
+		if p = strings.TrimSuffix(p, "-"); p != "" && !disabled[p] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// withAlwaysShown appends a zero-count placeholder for every prefix not
+// already covered by one of rigs.
+func withAlwaysShown(rigs []*rigOverview, prefixes []string) []*rigOverview {
+	covered := make(map[string]bool)
+	for _, rig := range rigs {
+		for _, p := range rig.Prefixes {
+			covered[p] = true
+		}
+	}
+	for _, p := range prefixes {
+		if covered[p] {
+			continue
+		}
+		covered[p] = true
+		name := p
+		if p == "hq" {
+			name = "town"
+		}
+		rigs = append(rigs, &rigOverview{Name: name, Prefixes: []string{p}, Placeholder: true})
+	}
+	return rigs
+}
+
+// discoverRigs lists one entry per beads directory: enabled routes first,
+// then directories only known from the prefix map (the scan, hq).
+func discoverRigs() []*rigOverview {
+	byDir := make(map[string]*rigOverview)
+	var rigs []*rigOverview
+	addRig := func(dir, name, path string) *rigOverview {
+		rig, ok := byDir[dir]
+		if !ok {
+			rig = &rigOverview{Name: name, Path: path, Prefixes: []string{}, BeadsDir: dir}
+			byDir[dir] = rig
+			rigs = append(rigs, rig)
+		}
+		return rig
+	}
+
+	for _, rt := range readRoutes() {
+		if rt.disabled() {
+			continue
+		}
+		name := rt.Path
+		if name == "." {
+			name = "town"
+		}
+		rig := addRig(routeBeadsDir(rt), name, rt.Path)
+		rig.Prefixes = append(rig.Prefixes, strings.TrimSuffix(rt.Prefix, "-"))
+	}
+	// Rigs found only by the directory scan (or hq) have no route entry.
+	for key, dir := range currentPrefixMap() {
+		if _, ok := byDir[dir]; ok {
+			continue
+		}
+		name, path := key, key
+		if key == "hq" {
+			name, path = "town", "."
+		}
+		rig := addRig(dir, name, path)
+		rig.Prefixes = append(rig.Prefixes, key)
+	}
+	return rigs
+}
+
+// loadRigState fills the on-disk state and open bead count of a rig.
+func loadRigState(ctx context.Context, rig *rigOverview) {
+	if info, err := os.Stat(filepath.Join(rig.BeadsDir, "beads.db")); err == nil {
+		rig.DBExists = true
+		mtime := info.ModTime().UTC()
+		rig.LastModified = &mtime
+	}
+	beads, err := bdList(ctx, rig.BeadsDir, []string{"--status=open"})
+	if err != nil {
+		rig.Error = err.Error()
+		return
+	}
+	rig.OpenCount = len(beads)
+}
+
+// handleRigs is /api/overview without gt status: one entry per rig with
+// its beads.db state, open count and health. ?probe=0 skips the health
+// probe for a faster listing.
+func handleRigs(w http.ResponseWriter, r *http.Request) {
+	probe := r.URL.Query().Get("probe") != "0"
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	grace := time.Duration(cfg.MissingGraceSec) * time.Second
+	rigs := discoverRigs()
+	var wg sync.WaitGroup
+	for _, rig := range rigs {
+		wg.Add(1)
+		go func(rig *rigOverview) {
+			defer wg.Done()
+			loadRigState(r.Context(), rig)
+			if probe {
+				rig.Health = debounceHealth(rig.BeadsDir, probeRig(r.Context(), rig), grace, time.Now())
+			}
+		}(rig)
+	}
+	wg.Wait()
+	rigs = withAlwaysShown(rigs, alwaysShownPrefixes(cfg))
+
+	sort.Slice(rigs, func(i, j int) bool { return rigs[i].Name < rigs[j].Name })
+	sendJSON(w, rigs, http.StatusOK)
+}
+
+func handleOverview(w http.ResponseWriter, r *http.Request) {
+	rigs := discoverRigs()
+
+	// gt status is shared by every rig; fetch it alongside the per-rig counts.
+	statusByName := make(map[string]json.RawMessage)
+	var statusErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		data, err := execCmdContext(r.Context(), "gt", []string{"status", "--json"}, nil)
+		if err != nil {
+			statusErr = err
+			return
+		}
+		var status struct {
+			Rigs []json.RawMessage `json:"rigs"`
+		}
+		if err := json.Unmarshal(data, &status); err != nil {
+			statusErr = err
+			return
+		}
+		for _, raw := range status.Rigs {
+			var named struct {
+				Name string `json:"name"`
+			}
+			if json.Unmarshal(raw, &named) == nil && named.Name != "" {
+				statusByName[named.Name] = raw
+			}
+		}
+	}()
+
+	for _, rig := range rigs {
+		wg.Add(1)
+		go func(rig *rigOverview) {
+			defer wg.Done()
+			loadRigState(r.Context(), rig)
+		}(rig)
+	}
+	wg.Wait()
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	rigs = withAlwaysShown(rigs, alwaysShownPrefixes(cfg))
+
+	for _, rig := range rigs {
+		if raw, ok := statusByName[rig.Name]; ok {
+			rig.Status = raw
+		} else if statusErr != nil && rig.Error == "" {
+			rig.Error = "gt status: " + statusErr.Error()
+		}
+	}
+
+	sort.Slice(rigs, func(i, j int) bool { return rigs[i].Name < rigs[j].Name })
+	sendJSON(w, rigs, http.StatusOK)
+}
+
+func handleCloseBead(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !validBeadID(id) {
+		sendError(w, fmt.Sprintf("invalid bead id %q", id), http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(body.Reason)
+
+	configMu.RLock()
+	requireReason := loadConfig().RequireCloseReason
+	configMu.RUnlock()
+	if requireReason && reason == "" {
+		sendError(w, "a close reason is required", http.StatusBadRequest)
+		return
+	}
+
+	args := []string{"close", id, "--json"}
+	if reason != "" {
+		args = append(args, "--reason="+reason)
+	}
+	// Deliberately not tied to r.Context(): a client navigating away should
+	// not kill bd halfway through a write.
+	data, err := execCmd("bd", args, map[string]string{"BEADS_DIR": beadsDirForID(id)})
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, data, http.StatusOK)
+}
+
+func handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	fileData, _ := os.ReadFile(configPath)
+	configMu.RUnlock()
+	if r.URL.Query().Get("withSources") == "1" {
+		sendJSON(w, map[string]any{
+			"config":  cfg,
+			"sources": configSources(fileData),
+			"flags":   flagOverrides,
+		}, http.StatusOK)
+		return
+	}
+	sendJSON(w, cfg, http.StatusOK)
+}
+
+// handlePostConfig applies a partial config: the body is deep-merged onto
+// the current config, so only the fields it contains change, at any
+// depth. A field set to null goes back to its default. Keys outside
+// clientSettings are rejected.
+func handlePostConfig(w http.ResponseWriter, r *http.Request) {
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxConfigImportBytes)).Decode(&patch); err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cfg, ok := applyClientConfig(w, patch, false); ok {
+		sendJSON(w, cfg, http.StatusOK)
+	}
+}
+
+// listenWithFallback listens on host:port, or when that port is taken
+// on the first free one of the next fallback ports. It returns the port
+// actually bound.
+func listenWithFallback(host string, port, fallback int) (net.Listener, int, error) {
+	for p := port; ; p++ {
+		ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(p)))
+		if err == nil {
+			return ln, p, nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) || p >= port+fallback {
+			return nil, 0, err
+		}
+	}
+}
+
+// probeInstance asks host:port for /health and reports whether a rigradar
+// answered, with the town root it serves. Anything else, including no
+// answer within timeout, counts as no instance.
+func probeInstance(host string, port int, timeout time.Duration) (string, bool) {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get("http://" + net.JoinHostPort(host, strconv.Itoa(port)) + "/health")
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+	var health struct {
+		Status string `json:"status"`
+		Town   string `json:"town"`
+		Engine string `json:"engine"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&health); err != nil {
+		return "", false
+	}
+	if health.Status == "" || health.Town == "" || health.Engine == "" {
+		return "", false
+	}
+	return health.Town, true
+}
+
+// serverProtocols enables unencrypted HTTP/2 next to HTTP/1.1 when
+// Config.H2C is set; nil keeps net/http's defaults.
+func serverProtocols(cfg Config) *http.Protocols {
+	if !cfg.H2C {
+		return nil
+	}
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetUnencryptedHTTP2(true)
+	return p
+}
+
+// checkLoopbackHost returns an error unless host only resolves to
+// loopback addresses. Wildcards such as 0.0.0.0 and :: are rejected.
+func checkLoopbackHost(host string) error {
+	host = strings.Trim(host, "[]")
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.LookupHost(host)
+		if err != nil {
+			return fmt.Errorf("cannot resolve host %q: %v", host, err)
+		}
+		for _, a := range addrs {
+			if ip := net.ParseIP(a); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("host %q has no addresses", host)
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return fmt.Errorf("host %q is not a loopback address (%s)", host, ip)
+		}
+	}
+	return nil
 }
 
 func openBrowser(url string) {
@@ -456,16 +2305,83 @@ func openBrowser(url string) {
 	exec.Command(cmd, args...).Start()
 }
 
+func registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /", handleIndex)
+	mux.HandleFunc("GET /new", handleNewBeadPage)
+	mux.HandleFunc("GET /b/{id}", handleBeadPermalink)
+	mux.HandleFunc("GET /api/validate-id/{id}", handleValidateID)
+	mux.HandleFunc("GET /api/", handleAPINotFound)
+	mux.HandleFunc("POST /api/", handleAPINotFound)
+	mux.Handle("GET /static/", staticHandler())
+	mux.HandleFunc("GET /health", handleHealth)
+	mux.HandleFunc("GET /health/deep", handleHealthDeep)
+	mux.HandleFunc("GET /livez", handleLivez)
+	mux.HandleFunc("GET /readyz", handleReadyz)
+	mux.HandleFunc("GET /api/ready", handleReady)
+	mux.HandleFunc("GET /api/status", handleStatus)
+	mux.HandleFunc("GET /api/beads", negotiateMsgpack(handleBeads))
+	mux.HandleFunc("GET /api/beads.rss", handleBeadsFeed)
+	mux.Handle("GET /api/beads/export.json", streaming(http.HandlerFunc(handleExportBeads)))
+	mux.HandleFunc("GET /api/beads/activity", handleActivity)
+	mux.HandleFunc("POST /api/beads/query", handleQueryBeads)
+	mux.HandleFunc("GET /api/bead/", handleBeadDetail)
+	mux.HandleFunc("GET /api/bead/{id}/blockers", handleBlockers)
+	mux.HandleFunc("GET /api/bd/{subcommand}", handleBdProxy)
+	mux.Handle("POST /api/gt/{command}/stream", streaming(requireMutation("gt", handleGtStream)))
+	mux.HandleFunc("GET /api/config", handleGetConfig)
+	mux.HandleFunc("POST /api/config", handlePostConfig)
+	mux.HandleFunc("POST /api/admin/drain", requireAdmin(handleDrain))
+	mux.HandleFunc("GET /api/config/export", handleExportConfig)
+	mux.HandleFunc("GET /api/config/schema", handleConfigSchema)
+	mux.HandleFunc("POST /api/config/import", handleImportConfig)
+	mux.HandleFunc("GET /api/diagnostics", handleDiagnostics)
+	mux.HandleFunc("GET /api/overview", handleOverview)
+	mux.HandleFunc("GET /api/overview/rigs", handleOverviewRigs)
+	mux.HandleFunc("GET /api/rigs", handleRigs)
+	mux.HandleFunc("GET /api/trends", handleTrends)
+	mux.HandleFunc("GET /api/identity", handleIdentity)
+	mux.HandleFunc("GET /api/focus", handleFocus)
+	mux.HandleFunc("GET /api/templates", handleTemplates)
+	mux.HandleFunc("GET /api/tags", handleTags)
+	mux.HandleFunc("GET /api/stats", handleStats)
+	mux.HandleFunc("GET /api/matrix", handleMatrix)
+	mux.HandleFunc("GET /api/alerts", handleAlerts)
+	mux.HandleFunc("GET /api/watched", handleWatched)
+	mux.HandleFunc("POST /api/watched/{id}/add", handleWatchBead(true))
+	mux.HandleFunc("POST /api/watched/{id}/remove", handleWatchBead(false))
+	mux.HandleFunc("POST /api/bead", requireMutation("create", handleCreateBead))
+	mux.HandleFunc("POST /api/beads/close", requireMutation("close", handleBulkClose))
+	mux.HandleFunc("POST /api/bead/{id}/close", requireMutation("close", handleCloseBead))
+	mux.HandleFunc("POST /api/bead/{id}/update", requireMutation("update", handleUpdateBead))
+}
+
 func main() {
 	port := flag.Int("port", 0, "Server port (overrides config.json)")
 	open := flag.Bool("open", false, "Open browser on start")
 	flag.Parse()
 
 	cfg := loadConfig()
+	setupLogging(cfg.LogFormat, os.Stderr)
+
+	warnMissingOverrides(cfg)
+	if cfg.DefaultRig != "" && defaultRig(cfg) == "hq" && strings.TrimSuffix(cfg.DefaultRig, "-") != "hq" {
+		log.Printf("warning: defaultRig %q is not a known prefix; using the town", cfg.DefaultRig)
+	}
+	if cfg.MaxConcurrentCommands > 0 {
+		cmdSlots = make(chan struct{}, cfg.MaxConcurrentCommands)
+	}
+	execWrapper = strings.Fields(cfg.ExecWrapper)
+	directRead = cfg.DirectRead
+	proxyEnv = validProxyEnv(cfg.ProxyEnv)
+	if len(cfg.ListWrapperKeys) > 0 {
+		listWrapperKeys = cfg.ListWrapperKeys
+	}
+	adminToken = os.Getenv("RIGRADAR_ADMIN_TOKEN")
 
 	listenPort := cfg.Server.Port
 	if *port != 0 {
 		listenPort = *port
+		flagOverrides["server.port"] = *port
 	}
 	if listenPort == 0 {
 		listenPort = 9292
@@ -475,29 +2391,58 @@ func main() {
 	if host == "" {
 		host = "localhost"
 	}
+	if !cfg.AllowPublicBind {
+		if err := checkLoopbackHost(host); err != nil {
+			log.Fatalf("Refusing to start: %v. Rigradar has no authentication and anyone who can reach this address can see your town; set \"allowPublicBind\": true in config.json if that is intended.", err)
+		}
+	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("GET /", handleIndex)
-	mux.HandleFunc("GET /health", handleHealth)
-	mux.HandleFunc("GET /api/ready", handleReady)
-	mux.HandleFunc("GET /api/status", handleStatus)
-	mux.HandleFunc("GET /api/beads", handleBeads)
-	mux.HandleFunc("GET /api/bead/", handleBeadDetail)
-	mux.HandleFunc("GET /api/config", handleGetConfig)
-	mux.HandleFunc("POST /api/config", handlePostConfig)
+	if cfg.InstanceCheck != "off" {
+		if town, ok := probeInstance(host, listenPort, 500*time.Millisecond); ok {
+			if cfg.InstanceCheck == "refuse" {
+				log.Fatalf("Refusing to start: rigradar is already running at http://%s:%d for town %s. Set \"instanceCheck\": \"warn\" in config.json to start anyway.", host, listenPort, town)
+			}
+			log.Printf("warning: rigradar is already running at http://%s:%d for town %s; both instances share config.json", host, listenPort, town)
+		}
+	}
 
 	addr := fmt.Sprintf("%s:%d", host, listenPort)
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      corsMiddleware(mux),
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		Handler:      buildHandler(),
+		ReadTimeout:  time.Duration(cfg.ReadTimeoutSec) * time.Second,
+		WriteTimeout: time.Duration(cfg.WriteTimeoutSec) * time.Second,
+		Protocols:    serverProtocols(cfg),
+	}
+
+	if extra := validExtraHeaders(cfg.ExtraHeaders); len(extra) > 0 {
+		server.Handler = extraHeadersMiddleware(extra, server.Handler)
 	}
 
 	// Graceful shutdown on interrupt
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	if cfg.IdleTimeoutMinutes > 0 {
+		timeout := time.Duration(cfg.IdleTimeoutMinutes) * time.Minute
+		idle := newIdleTimer(timeout, cfg.IdleIgnorePaths, func() {
+			log.Printf("No requests for %s, shutting down", timeout)
+			stop()
+		})
+		server.Handler = idle.middleware(server.Handler)
+	}
+
+	startWatcher(ctx, cfg)
+	invalidateOnHUP(ctx)
+	startPrewarm(ctx, cfg)
+	startBackgroundRefresh(ctx, cfg)
+	startSchemaDetection(ctx)
+
+	interval, size := trendSettings(cfg)
+	trends = newTrendBuffer(size)
+	startTrendSampler(ctx, interval)
+	startHistoryPruner(ctx, historyRetention(cfg))
+
 	go func() {
 		<-ctx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -505,6 +2450,18 @@ func main() {
 		server.Shutdown(shutdownCtx)
 	}()
 
+	ln, boundPort, err := listenWithFallback(host, listenPort, cfg.PortFallback)
+	if err != nil {
+		if errors.Is(err, syscall.EADDRINUSE) {
+			log.Fatalf("Port %d is already in use (is rigradar already running?). Use --port, or set \"portFallback\": N in config.json to try the next N ports.", listenPort)
+		}
+		log.Fatalf("Server error: %v", err)
+	}
+	if boundPort != listenPort {
+		log.Printf("Port %d is in use, using %d instead", listenPort, boundPort)
+		addr = fmt.Sprintf("%s:%d", host, boundPort)
+	}
+
 	fmt.Printf("Rigradar running at http://%s\n", addr)
 	fmt.Printf("Town root: %s\n", townRoot)
 	fmt.Printf("Engine: Go\n")
@@ -513,7 +2470,7 @@ func main() {
 		openBrowser(fmt.Sprintf("http://%s", addr))
 	}
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server error: %v", err)
 	}
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// handleBeadMarkdown serves /api/bead/{id}.md: the bead as a Markdown
+// document for pasting into docs and PR descriptions. The bead is looked
+// up as for the JSON detail, and errors are the same JSON errors.
+func handleBeadMarkdown(w http.ResponseWriter, r *http.Request, id string) {
+	data, err := loadBead(r, id)
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+	d, err := detailView(data)
+	if err != nil {
+		sendError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write([]byte(beadMarkdown(d)))
+}
+
+// beadMarkdown renders a heading, a metadata table of the fields that are
+// set, and the description, which is already Markdown in bd.
+func beadMarkdown(d beadDetail) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s: %s\n\n", mdInline(d.ID), mdInline(d.Title))
+
+	priority := ""
+	if d.Priority != nil {
+		priority = fmt.Sprintf("P%d", *d.Priority)
+	}
+	rows := [][2]string{
+		{"Status", d.Status},
+		{"Type", d.Type},
+		{"Priority", priority},
+		{"Assignee", d.Assignee},
+		{"Created", d.Created},
+		{"Updated", d.Updated},
+		{"Blocked by", strings.Join(d.Blockers, ", ")},
+	}
+	b.WriteString("| Field | Value |\n|-------|-------|\n")
+	for _, row := range rows {
+		if row[1] != "" {
+			fmt.Fprintf(&b, "| %s | %s |\n", row[0], mdInline(row[1]))
+		}
+	}
+
+	if body := strings.TrimSpace(d.Body); body != "" {
+		b.WriteString("\n" + body + "\n")
+	}
+	return b.String()
+}
+
+// mdInline escapes characters that would turn plain text into Markdown
+// formatting, and folds line breaks so it is safe in headings and table
+// cells.
+func mdInline(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune("\\`*_[]<>#|", c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBeadMarkdown(t *testing.T) {
+	origMap, origRoot := prefixMap, townRoot
+	defer func() { prefixMap, townRoot = origMap, origRoot }()
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"ri": filepath.Join(townRoot, ".beads")}
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","title":"Fix *all* the | pipes","status":"open","issue_type":"bug","priority":1,
+ "assignee":"nux","description":"Steps:\\n\\n1. run it","dependencies":[{"depends_on_id":"ri-0","type":"blocks"}]}]'`)
+
+	req := httptest.NewRequest("GET", "/api/bead/ri-1.md", nil)
+	w := httptest.NewRecorder()
+	handleBeadDetail(w, req)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	want := `# ri-1: Fix \*all\* the \| pipes
+
+| Field | Value |
+|-------|-------|
+| Status | open |
+| Type | bug |
+| Priority | P1 |
+| Assignee | nux |
+| Blocked by | ri-0 |
+
+Steps:
+
+1. run it
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("markdown =\n%s\nwant\n%s", got, want)
+	}
+
+	fakeBin(t, "bd", "echo 'no issue found' >&2; exit 1")
+	w = httptest.NewRecorder()
+	handleBeadDetail(w, httptest.NewRequest("GET", "/api/bead/ri-9.md", nil))
+	if w.Code != 500 || !strings.Contains(w.Body.String(), `"error"`) {
+		t.Errorf("missing bead: status %d, body %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type requestIDKey struct{}
+
+// requestIDFrom returns the request id attached by requestIDMiddleware.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID accepts short ids made of characters that are safe to
+// echo into headers and log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDMiddleware honors an incoming X-Request-Id (or generates one),
+// echoes it on the response and stores it in the request context.
+// sendError picks it up from the response header.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-Id", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// statusRecorder captures the response status for request logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// jsonLogger is set when Config.LogFormat is "json"; nil keeps the plain
+// text request log.
+var jsonLogger *slog.Logger
+
+// setupLogging switches all logging to one JSON object per line when
+// format is "json". The standard log package is routed through the same
+// handler, so existing log.Printf calls come out as JSON too.
+func setupLogging(format string, out io.Writer) {
+	switch format {
+	case "", "text":
+		return
+	case "json":
+	default:
+		log.Printf("Warning: unknown logFormat %q, using text", format)
+		return
+	}
+	h := slog.NewJSONHandler(out, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Key = "ts"
+			}
+			return a
+		},
+	})
+	jsonLogger = slog.New(h)
+	slog.SetDefault(jsonLogger)
+}
+
+// logRequests writes one log line per request, tagged with its request id.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+		if jsonLogger == nil {
+			log.Printf("%s %s %d %s id=%s", r.Method, r.URL.Path, rec.status, elapsed.Round(time.Millisecond), requestIDFrom(r.Context()))
+			return
+		}
+		level := slog.LevelInfo
+		if rec.status >= 500 {
+			level = slog.LevelError
+		}
+		jsonLogger.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("durationMs", elapsed.Milliseconds()),
+			slog.String("requestId", requestIDFrom(r.Context())),
+		)
+	})
+}
+
+// buildHandler wires every route and the middleware chain.
+func buildHandler() http.Handler {
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+	return requestIDMiddleware(logRequests(trackInFlight(htmlErrors(apiVersion(corsMiddleware(mux))))))
+}
+
+// apiVersion serves /api/v1/... as /api/...: the routes are registered
+// once, as the current API, and the bare /api/ is an alias for v1. An
+// incompatible v2 would get its own routes rather than this rewrite.
+func apiVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/api/v1/")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		u := *r.URL
+		u.Path = "/api/" + rest
+		if raw, ok := strings.CutPrefix(u.RawPath, "/api/v1/"); ok {
+			u.RawPath = "/api/" + raw
+		}
+		r2.URL = &u
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// prefersHTML reports whether the Accept header ranks text/html above
+// JSON, as a browser navigating to a URL does. A bare */* (fetch's
+// default) does not count.
+func prefersHTML(r *http.Request) bool {
+	htmlQ, jsonQ, anyQ := 0.0, -1.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		switch strings.TrimSpace(mediaType) {
+		case "text/html":
+			htmlQ = q
+		case "application/json":
+			jsonQ = q
+		case "*/*":
+			anyQ = q
+		}
+	}
+	if jsonQ < 0 {
+		jsonQ = anyQ
+	}
+	return htmlQ > 0 && htmlQ > jsonQ
+}
+
+// htmlErrorWriter holds back a JSON error response so htmlErrors can
+// replace it with a page.
+type htmlErrorWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (h *htmlErrorWriter) WriteHeader(code int) {
+	if code >= 400 && strings.HasPrefix(h.Header().Get("Content-Type"), "application/json") {
+		h.status = code
+		return
+	}
+	h.ResponseWriter.WriteHeader(code)
+}
+
+func (h *htmlErrorWriter) Write(p []byte) (int, error) {
+	if h.status != 0 {
+		return h.body.Write(p)
+	}
+	return h.ResponseWriter.Write(p)
+}
+
+func (h *htmlErrorWriter) Unwrap() http.ResponseWriter {
+	return h.ResponseWriter
+}
+
+// htmlErrors turns JSON error responses outside /api into a minimal HTML
+// page when a browser asks for HTML. /api responses always stay JSON.
+func htmlErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") || !prefersHTML(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		hw := &htmlErrorWriter{ResponseWriter: w}
+		next.ServeHTTP(hw, r)
+		if hw.status == 0 {
+			return
+		}
+
+		// Handlers report errors as {"error"}; health checks list problems.
+		var body struct {
+			Error    string   `json:"error"`
+			Problems []string `json:"problems"`
+		}
+		json.Unmarshal(hw.body.Bytes(), &body)
+		msgs := body.Problems
+		if body.Error != "" {
+			msgs = append([]string{body.Error}, msgs...)
+		}
+		title := html.EscapeString(fmt.Sprintf("%d %s", hw.status, http.StatusText(hw.status)))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(hw.status)
+		fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><title>%s - Rigradar</title></head>
+<body style="font-family: monospace; background: #1a1a2e; color: #e0e0e0; padding: 2em">
+<h1>%s</h1>
+`, title, title)
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(msg))
+		}
+		if id := w.Header().Get("X-Request-Id"); id != "" {
+			fmt.Fprintf(w, "<p style=\"color: #8892a8\">Request id: %s</p>\n", html.EscapeString(id))
+		}
+		fmt.Fprint(w, "<p><a href=\"/\" style=\"color: #4fc3f7\">Back to the dashboard</a></p>\n</body>\n</html>\n")
+	})
+}
+
+// protectedHeader reports headers that handlers and the CORS/request-id
+// middleware own; ExtraHeaders may not set them.
+func protectedHeader(name string) bool {
+	switch name {
+	case "Content-Type", "Content-Length", "X-Request-Id":
+		return true
+	}
+	return strings.HasPrefix(name, "Access-Control-")
+}
+
+// validHeaderName reports whether name is an RFC 7230 token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validExtraHeaders canonicalizes Config.ExtraHeaders, logging and
+// dropping invalid names, protected names, and values with line breaks.
+func validExtraHeaders(headers map[string]string) map[string]string {
+	valid := make(map[string]string, len(headers))
+	for name, value := range headers {
+		canon := http.CanonicalHeaderKey(name)
+		switch {
+		case !validHeaderName(name):
+			log.Printf("Warning: extraHeaders: invalid header name %q, ignoring", name)
+		case protectedHeader(canon):
+			log.Printf("Warning: extraHeaders: %s is set by rigradar itself, ignoring", canon)
+		case strings.ContainsAny(value, "\r\n"):
+			log.Printf("Warning: extraHeaders: value for %s contains a line break, ignoring", canon)
+		default:
+			valid[canon] = value
+		}
+	}
+	return valid
+}
+
+// extraHeadersMiddleware sets the configured headers on every response
+// before the handler runs.
+func extraHeadersMiddleware(headers map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range headers {
+			w.Header().Set(name, value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// streaming exempts a long-lived route (SSE, streamed exports) from the
+// server's WriteTimeout by clearing the connection's write deadline.
+func streaming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Printf("streaming %s: cannot clear write deadline: %v", r.URL.Path, err)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// idleTimer fires onIdle once no counted request has been open for
+// timeout.
+type idleTimer struct {
+	timeout time.Duration
+	ignore  map[string]bool
+	timer   *time.Timer
+
+	mu     sync.Mutex
+	active int // counted requests in flight
+}
+
+func newIdleTimer(timeout time.Duration, ignorePaths []string, onIdle func()) *idleTimer {
+	ignore := make(map[string]bool, len(ignorePaths))
+	for _, p := range ignorePaths {
+		ignore[p] = true
+	}
+	return &idleTimer{timeout: timeout, ignore: ignore, timer: time.AfterFunc(timeout, onIdle)}
+}
+
+// middleware holds the timer while any request is in flight and restarts
+// it when the last one finishes, so a long stream or SSE connection keeps
+// the instance alive. Requests to ignored paths don't count, so health
+// probes alone don't keep an idle instance alive.
+func (t *idleTimer) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.ignore[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		t.mu.Lock()
+		t.active++
+		t.timer.Stop()
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			t.active--
+			if t.active == 0 {
+				t.timer.Reset(t.timeout)
+			}
+			t.mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
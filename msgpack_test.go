@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// decodeMsgpack reads back the subset of MessagePack encodeMsgpack writes,
+// with numbers as float64 like encoding/json.
+func decodeMsgpack(r *bytes.Reader) (any, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	readN := func(n int) []byte {
+		p := make([]byte, n)
+		r.Read(p)
+		return p
+	}
+	str := func(n int) (any, error) { return string(readN(n)), nil }
+	list := func(n int) (any, error) {
+		out := make([]any, n)
+		for i := range out {
+			v, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	dict := func(n int) (any, error) {
+		out := make(map[string]any, n)
+		for range n {
+			k, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			out[k.(string)] = v
+		}
+		return out, nil
+	}
+	switch {
+	case b < 0x80:
+		return float64(b), nil
+	case b >= 0xe0:
+		return float64(int8(b)), nil
+	case b&0xe0 == 0xa0:
+		return str(int(b & 0x1f))
+	case b&0xf0 == 0x90:
+		return list(int(b & 0x0f))
+	case b&0xf0 == 0x80:
+		return dict(int(b & 0x0f))
+	}
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xd0:
+		return float64(int8(readN(1)[0])), nil
+	case 0xd1:
+		return float64(int16(binary.BigEndian.Uint16(readN(2)))), nil
+	case 0xd2:
+		return float64(int32(binary.BigEndian.Uint32(readN(4)))), nil
+	case 0xd3:
+		return float64(int64(binary.BigEndian.Uint64(readN(8)))), nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(readN(8))), nil
+	case 0xd9:
+		return str(int(readN(1)[0]))
+	case 0xda:
+		return str(int(binary.BigEndian.Uint16(readN(2))))
+	case 0xdb:
+		return str(int(binary.BigEndian.Uint32(readN(4))))
+	case 0xdc:
+		return list(int(binary.BigEndian.Uint16(readN(2))))
+	case 0xdd:
+		return list(int(binary.BigEndian.Uint32(readN(4))))
+	case 0xde:
+		return dict(int(binary.BigEndian.Uint16(readN(2))))
+	case 0xdf:
+		return dict(int(binary.BigEndian.Uint32(readN(4))))
+	}
+	return nil, fmt.Errorf("unexpected msgpack byte %#x", b)
+}
+
+func TestBeadsMsgpack(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	long := string(bytes.Repeat([]byte("x"), 300))
+	fakeBin(t, "bd", `echo '[
+ {"id":"ri-1","title":"`+long+`","priority":0,"estimate":-200,"score":1.5,"labels":["a","b"],"assignee":null,"pinned":true},
+ {"id":"ri-2","priority":70000,"offset":-40000}
+]'`)
+
+	req := httptest.NewRequest("GET", "/api/beads", nil)
+	w := httptest.NewRecorder()
+	negotiateMsgpack(handleBeads)(w, req)
+	var want any
+	json.Unmarshal(w.Body.Bytes(), &want)
+
+	req = httptest.NewRequest("GET", "/api/beads", nil)
+	req.Header.Set("Accept", "application/msgpack, application/json;q=0.5")
+	w = httptest.NewRecorder()
+	negotiateMsgpack(handleBeads)(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "application/msgpack" {
+		t.Fatalf("Content-Type = %q, want application/msgpack", ct)
+	}
+	got, err := decodeMsgpack(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("msgpack round trip = %v\nwant %v", got, want)
+	}
+}
+
+func TestAcceptsMsgpack(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                               false,
+		"application/json":               false,
+		"application/msgpack":            true,
+		"application/x-msgpack;q=1":      true,
+		"application/msgpack;q=0":        false,
+		"text/html, application/msgpack": true,
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", header)
+		if got := acceptsMsgpack(req); got != want {
+			t.Errorf("acceptsMsgpack(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// beadMatcher reports whether a decoded bead satisfies a filter expression.
+type beadMatcher func(bead map[string]any) bool
+
+// queryFieldNames maps the friendlier names accepted in filter expressions
+// onto bd's JSON fields. Anything else is looked up verbatim.
+var queryFieldNames = map[string]string{
+	"type": "issue_type",
+	"body": "description",
+}
+
+// maxQueryDepth bounds and/or/not nesting so a hostile body can't recurse
+// without limit.
+const maxQueryDepth = 32
+
+// parseQuery compiles a filter expression. An expression is an object whose
+// keys are either "and"/"or" (arrays of expressions), "not" (one
+// expression), or a bead field. A field maps to a value to match exactly or
+// to {"contains": "text"} for a case-insensitive substring match; array
+// fields such as labels match when any element does. Several keys in one
+// object must all hold.
+func parseQuery(data json.RawMessage, depth int) (beadMatcher, error) {
+	if depth > maxQueryDepth {
+		return nil, fmt.Errorf("expression nested deeper than %d levels", maxQueryDepth)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return nil, fmt.Errorf("expression must be an object: %s", data)
+	}
+	if len(obj) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []beadMatcher
+	for _, key := range keys {
+		raw := obj[key]
+		switch key {
+		case "and", "or":
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil || len(items) == 0 {
+				return nil, fmt.Errorf("%q needs a non-empty array of expressions", key)
+			}
+			subs := make([]beadMatcher, len(items))
+			for i, item := range items {
+				m, err := parseQuery(item, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				subs[i] = m
+			}
+			if key == "and" {
+				parts = append(parts, allOf(subs))
+			} else {
+				parts = append(parts, anyOf(subs))
+			}
+		case "not":
+			m, err := parseQuery(raw, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, func(b map[string]any) bool { return !m(b) })
+		default:
+			m, err := fieldMatcher(key, raw)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, m)
+		}
+	}
+	return allOf(parts), nil
+}
+
+func allOf(ms []beadMatcher) beadMatcher {
+	return func(b map[string]any) bool {
+		for _, m := range ms {
+			if !m(b) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func anyOf(ms []beadMatcher) beadMatcher {
+	return func(b map[string]any) bool {
+		for _, m := range ms {
+			if m(b) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func fieldMatcher(name string, raw json.RawMessage) (beadMatcher, error) {
+	field := name
+	if f, ok := queryFieldNames[name]; ok {
+		field = f
+	}
+	var want any
+	if err := json.Unmarshal(raw, &want); err != nil {
+		return nil, fmt.Errorf("field %q: %v", name, err)
+	}
+	if op, ok := want.(map[string]any); ok {
+		sub, ok := op["contains"].(string)
+		if len(op) != 1 || !ok {
+			return nil, fmt.Errorf("field %q: only {\"contains\": \"text\"} is supported as an operator", name)
+		}
+		sub = strings.ToLower(sub)
+		return func(b map[string]any) bool {
+			return anyValue(b[field], func(v any) bool {
+				s, ok := v.(string)
+				return ok && strings.Contains(strings.ToLower(s), sub)
+			})
+		}, nil
+	}
+	switch want.(type) {
+	case []any:
+		return nil, fmt.Errorf("field %q: arrays are not a valid match value", name)
+	}
+	return func(b map[string]any) bool {
+		return anyValue(b[field], func(v any) bool { return v == want })
+	}, nil
+}
+
+// anyValue applies match to v, or to each element when v is an array.
+func anyValue(v any, match func(any) bool) bool {
+	if list, ok := v.([]any); ok {
+		for _, item := range list {
+			if match(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return match(v)
+}
+
+// handleQueryBeads evaluates a filter expression (see parseQuery) against
+// the merged bead list from every rig. It is kept apart from GET
+// /api/beads so the flat query parameters stay simple.
+func handleQueryBeads(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	match, err := parseQuery(body, 0)
+	if err != nil {
+		sendError(w, "invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	ctx, cancel := withBeadsBudget(r.Context())
+	defer cancel()
+	dirs, _ := limitDirs(beadDirs(), cfg.MaxRigs)
+	raw, errs, partial := fetchBeads(ctx, dirs, nil, beadsTTL(cfg))
+
+	beads := []json.RawMessage{}
+	for _, data := range raw {
+		var bead map[string]any
+		if json.Unmarshal(data, &bead) == nil && match(bead) {
+			beads = append(beads, data)
+		}
+	}
+	if partial {
+		w.Header().Set("X-Rigradar-Partial", "true")
+	}
+	sendBeads(w, beads, errs, nil, r.URL.Query().Get("verbose") == "1")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	beads := []map[string]any{
+		{"id": "ri-1", "status": "open", "priority": 2.0, "issue_type": "bug", "title": "Crash on start"},
+		{"id": "ri-2", "status": "closed", "priority": 0.0, "issue_type": "task", "labels": []any{"ops"}},
+		{"id": "ri-3", "status": "in_progress", "priority": 1.0, "issue_type": "bug", "title": "Slow crash report"},
+		{"id": "ri-4", "status": "open", "priority": 3.0, "issue_type": "task"},
+	}
+	for expr, want := range map[string]string{
+		`{"status":"open"}`:                           "ri-1,ri-4",
+		`{"or":[{"status":"closed"},{"priority":1}]}`: "ri-2,ri-3",
+		`{"and":[{"type":"bug"},{"or":[{"status":"open"},{"title":{"contains":"SLOW"}}]}]}`: "ri-1,ri-3",
+		`{"not":{"or":[{"type":"bug"},{"labels":"ops"}]}}`:                                  "ri-4",
+		`{"type":"task","status":"open"}`:                                                   "ri-4",
+	} {
+		match, err := parseQuery(json.RawMessage(expr), 0)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		var got []string
+		for _, b := range beads {
+			if match(b) {
+				got = append(got, b["id"].(string))
+			}
+		}
+		if strings.Join(got, ",") != want {
+			t.Errorf("%s matched %v, want %s", expr, got, want)
+		}
+	}
+
+	for _, bad := range []string{`[]`, `{}`, `{"or":[]}`, `{"or":{"status":"open"}}`, `{"title":{"regex":"x"}}`, `{"labels":["a"]}`} {
+		if _, err := parseQuery(json.RawMessage(bad), 0); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestHandleQueryBeads(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","status":"open","priority":2},{"id":"ri-2","status":"closed","priority":0}]'`)
+
+	w := httptest.NewRecorder()
+	handleQueryBeads(w, httptest.NewRequest("POST", "/api/beads/query", strings.NewReader(`{"or":[{"priority":0},{"status":"blocked"}]}`)))
+	var beads []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &beads); err != nil || len(beads) != 1 || beads[0].ID != "ri-2" {
+		t.Errorf("status %d, body %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	handleQueryBeads(w, httptest.NewRequest("POST", "/api/beads/query", strings.NewReader(`{"or":"x"}`)))
+	if w.Code != 400 {
+		t.Errorf("invalid filter status = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// dangerousBlocks are raw HTML elements dropped from a body together with
+// their content before it is rendered. Everything else that looks like
+// HTML is escaped and shows as text.
+var dangerousBlocks = []string{"script", "style", "iframe", "object", "embed", "noscript", "template"}
+
+// renderMarkdown converts a bead body to HTML for ?renderBody=1. It is safe
+// by construction: all text is escaped and only the tags below are ever
+// produced. Supported: ATX headings, paragraphs, - / * / + and numbered
+// lists, > quotes, ``` fences, `code`, **strong**, *em* and [links](url)
+// with http(s), mailto or relative targets.
+func renderMarkdown(src string) string {
+	lines := strings.Split(strings.ReplaceAll(stripDangerousBlocks(src), "\r\n", "\n"), "\n")
+	var b strings.Builder
+	var para []string
+	list := "" // "ul" or "ol" while inside a list
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + renderInline(strings.Join(para, " ")) + "</p>\n")
+			para = nil
+		}
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case trimmed == "":
+			flush()
+		case headingLevel(trimmed) > 0:
+			flush()
+			n := headingLevel(trimmed)
+			tag := "h" + strconv.Itoa(n)
+			b.WriteString("<" + tag + ">" + renderInline(strings.TrimSpace(trimmed[n:])) + "</" + tag + ">\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			b.WriteString("<blockquote><p>" + renderInline(strings.Join(quote, " ")) + "</p></blockquote>\n")
+		default:
+			kind, item := listItem(trimmed)
+			if kind == "" {
+				if list != "" {
+					flush()
+				}
+				para = append(para, trimmed)
+				continue
+			}
+			if len(para) > 0 || list != kind {
+				flush()
+				b.WriteString("<" + kind + ">\n")
+				list = kind
+			}
+			b.WriteString("<li>" + renderInline(item) + "</li>\n")
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// headingLevel returns n for a line starting with n (1-6) '#' and a space.
+func headingLevel(line string) int {
+	n := 0
+	for n < len(line) && line[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || n == len(line) || line[n] != ' ' {
+		return 0
+	}
+	return n
+}
+
+// listItem reports whether line is a list item ("ul" or "ol") and its text.
+func listItem(line string) (string, string) {
+	if len(line) > 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return "ul", strings.TrimSpace(line[2:])
+	}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && strings.HasPrefix(line[digits:], ". ") {
+		return "ol", strings.TrimSpace(line[digits+2:])
+	}
+	return "", ""
+}
+
+// renderInline renders code spans, links, strong and em within one block.
+func renderInline(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		switch {
+		case s[0] == '`':
+			if end := strings.IndexByte(s[1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(s[1:1+end]) + "</code>")
+				s = s[end+2:]
+				continue
+			}
+		case s[0] == '[':
+			if text, target, rest, ok := cutLink(s); ok {
+				if safeURL(target) {
+					b.WriteString(`<a href="` + html.EscapeString(target) + `" rel="noopener noreferrer">` + renderInline(text) + "</a>")
+				} else {
+					b.WriteString(renderInline(text))
+				}
+				s = rest
+				continue
+			}
+		case strings.HasPrefix(s, "**"):
+			if end := strings.Index(s[2:], "**"); end > 0 {
+				b.WriteString("<strong>" + renderInline(s[2:2+end]) + "</strong>")
+				s = s[end+4:]
+				continue
+			}
+		case s[0] == '*':
+			if end := strings.IndexByte(s[1:], '*'); end > 0 {
+				b.WriteString("<em>" + renderInline(s[1:1+end]) + "</em>")
+				s = s[end+2:]
+				continue
+			}
+		}
+		// Plain text up to the next character that may start markup.
+		n := 1 + strings.IndexAny(s[1:], "`[*")
+		if n == 0 {
+			n = len(s)
+		}
+		b.WriteString(html.EscapeString(s[:n]))
+		s = s[n:]
+	}
+	return b.String()
+}
+
+// cutLink splits "[text](target)rest".
+func cutLink(s string) (text, target, rest string, ok bool) {
+	mid := strings.Index(s, "](")
+	if mid < 0 {
+		return "", "", "", false
+	}
+	end := strings.IndexByte(s[mid+2:], ')')
+	if end < 0 {
+		return "", "", "", false
+	}
+	return s[1:mid], strings.TrimSpace(s[mid+2 : mid+2+end]), s[mid+3+end:], true
+}
+
+// safeURL allows http(s) and mailto links and scheme-less (relative) ones,
+// so javascript: and data: targets never become hrefs.
+func safeURL(target string) bool {
+	lower := strings.ToLower(target)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "mailto:") {
+		return true
+	}
+	colon := strings.IndexByte(lower, ':')
+	return colon < 0 || strings.IndexAny(lower[:colon], "/?#") >= 0
+}
+
+// stripDangerousBlocks removes dangerousBlocks elements and their content,
+// case-insensitively. An unclosed element runs to the end of the body.
+func stripDangerousBlocks(s string) string {
+	for _, tag := range dangerousBlocks {
+		for {
+			lower := strings.ToLower(s)
+			start := strings.Index(lower, "<"+tag)
+			if start < 0 {
+				break
+			}
+			end := len(s)
+			if i := strings.Index(lower[start:], "</"+tag); i >= 0 {
+				end = start + i
+				if gt := strings.IndexByte(lower[end:], '>'); gt >= 0 {
+					end += gt + 1
+				} else {
+					end = len(s)
+				}
+			}
+			s = s[:start] + s[end:]
+		}
+	}
+	return s
+}
+
+// withBodyHTML adds "bodyHtml", the rendered description, to each bead in
+// `bd show` output (an object or an array of them).
+func withBodyHTML(data json.RawMessage) json.RawMessage {
+	addHTML := func(bead map[string]json.RawMessage) {
+		var body string
+		json.Unmarshal(bead["description"], &body)
+		bead["bodyHtml"], _ = json.Marshal(renderMarkdown(body))
+	}
+	var out []byte
+	var err error
+	var list []map[string]json.RawMessage
+	var bead map[string]json.RawMessage
+	switch {
+	case json.Unmarshal(data, &list) == nil:
+		for _, b := range list {
+			addHTML(b)
+		}
+		out, err = json.Marshal(list)
+	case json.Unmarshal(data, &bead) == nil:
+		addHTML(bead)
+		out, err = json.Marshal(bead)
+	default:
+		return data
+	}
+	if err != nil {
+		return data
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	got := renderMarkdown("# Plan\n\nDo **this** and *that*, see `cfg.go`.\n\n- one\n- [docs](https://example.com/a?b=1&c=2)\n\n```\nif a < b {}\n```\n")
+	want := `<h1>Plan</h1>
+<p>Do <strong>this</strong> and <em>that</em>, see <code>cfg.go</code>.</p>
+<ul>
+<li>one</li>
+<li><a href="https://example.com/a?b=1&amp;c=2" rel="noopener noreferrer">docs</a></li>
+</ul>
+<pre><code>if a &lt; b {}</code></pre>
+`
+	if got != want {
+		t.Errorf("renderMarkdown =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderMarkdownSanitizes(t *testing.T) {
+	got := renderMarkdown("Hi <SCRIPT>alert(1)</script> there\n\n<img src=x onerror=alert(2)>\n\n[click](javascript:alert(3)) <style>body{}</style>")
+	for _, bad := range []string{"<script", "<SCRIPT", "alert(1)", "<img", "href=\"javascript", "body{}"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("rendered body contains %q:\n%s", bad, got)
+		}
+	}
+	if !strings.Contains(got, "&lt;img src=x onerror=alert(2)&gt;") || !strings.Contains(got, "click") {
+		t.Errorf("other HTML should be escaped text and link text kept:\n%s", got)
+	}
+}
+
+func TestBeadDetailRenderBody(t *testing.T) {
+	origMap, origRoot := prefixMap, townRoot
+	defer func() { prefixMap, townRoot = origMap, origRoot }()
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"ri": filepath.Join(townRoot, ".beads")}
+	fakeBin(t, "bd", `echo '[{"id":"ri-1","title":"t","description":"**bold**<script>x()</script>"}]'`)
+
+	for _, view := range []string{"", "&view=detail"} {
+		w := httptest.NewRecorder()
+		handleBeadDetail(w, httptest.NewRequest("GET", "/api/bead/ri-1?renderBody=1"+view, nil))
+		var bead map[string]any
+		if view == "" {
+			var list []map[string]any
+			json.Unmarshal(w.Body.Bytes(), &list)
+			if len(list) == 1 {
+				bead = list[0]
+			}
+		} else {
+			json.Unmarshal(w.Body.Bytes(), &bead)
+		}
+		if bead["bodyHtml"] != "<p><strong>bold</strong></p>\n" {
+			t.Errorf("view %q: bodyHtml = %q", view, bead["bodyHtml"])
+		}
+		raw := bead["description"]
+		if view != "" {
+			raw = bead["body"]
+		}
+		if raw != "**bold**<script>x()</script>" {
+			t.Errorf("view %q: raw body = %q, want it unchanged", view, raw)
+		}
+	}
+}
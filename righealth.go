@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// rigProbeTimeout bounds the per-rig health probe.
+const rigProbeTimeout = 5 * time.Second
+
+// rigHealth is the outcome of probing one rig: "ok", "degraded" (usable,
+// but something is off) or "broken" (bd cannot read it).
+type rigHealth struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// probeRig checks that the rig's directory matches routes.jsonl, that it
+// has a beads.db, and that `bd list --limit=1` succeeds there.
+func probeRig(ctx context.Context, rig *rigOverview) *rigHealth {
+	// Routed rigs keep their beads under the route's path.
+	if routeDir := filepath.Join(townRoot, rig.Path); rig.Path != "." && filepath.Dir(rig.BeadsDir) == routeDir {
+		if info, err := os.Stat(routeDir); err != nil || !info.IsDir() {
+			return &rigHealth{"broken", "routes.jsonl points at missing directory " + rig.Path}
+		}
+	}
+	if info, err := os.Stat(rig.BeadsDir); err != nil || !info.IsDir() {
+		return &rigHealth{"broken", "beads directory missing: " + rig.BeadsDir}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rigProbeTimeout)
+	defer cancel()
+	if _, err := bdList(ctx, rig.BeadsDir, []string{"--limit=1"}); err != nil {
+		return &rigHealth{"broken", err.Error()}
+	}
+	if _, err := os.Stat(filepath.Join(rig.BeadsDir, "beads.db")); err != nil {
+		return &rigHealth{"degraded", "no beads.db"}
+	}
+	return &rigHealth{Status: "ok"}
+}
+
+// rigFailures remembers, per beads dir, when the current run of broken
+// probes started and the last status seen before it.
+var rigFailures = struct {
+	mu    sync.Mutex
+	since map[string]time.Time
+	last  map[string]*rigHealth
+}{since: make(map[string]time.Time), last: make(map[string]*rigHealth)}
+
+// debounceHealth holds back a "broken" result until the dir has failed
+// for longer than grace across consecutive probes, reporting the last
+// good status (default ok) meanwhile. This rides out networked storage
+// blips. A zero grace reports failures immediately.
+func debounceHealth(dir string, h *rigHealth, grace time.Duration, now time.Time) *rigHealth {
+	rigFailures.mu.Lock()
+	defer rigFailures.mu.Unlock()
+	if h.Status != "broken" {
+		delete(rigFailures.since, dir)
+		rigFailures.last[dir] = h
+		return h
+	}
+	since, ok := rigFailures.since[dir]
+	if !ok {
+		since = now
+		rigFailures.since[dir] = since
+	}
+	if grace <= 0 || now.Sub(since) >= grace {
+		return h
+	}
+	held := rigHealth{Status: "ok"}
+	if last := rigFailures.last[dir]; last != nil {
+		held = *last
+	}
+	held.Reason = fmt.Sprintf("failing for %s, within missingGraceSec: %s", now.Sub(since).Round(time.Second), h.Reason)
+	return &held
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRigsHealth(t *testing.T) {
+	origRoot, origMap := townRoot, prefixMap
+	defer func() { townRoot, prefixMap = origRoot, origMap }()
+	townRoot = t.TempDir()
+	os.MkdirAll(filepath.Join(townRoot, ".beads"), 0o755)
+	os.WriteFile(filepath.Join(townRoot, ".beads", "routes.jsonl"), []byte(`{"prefix":"hq-","path":"."}
+{"prefix":"ok-","path":"good"}
+{"prefix":"bk-","path":"bad"}
+{"prefix":"gn-","path":"gone"}
+`), 0o644)
+	for _, rig := range []string{"good", "bad"} {
+		os.MkdirAll(filepath.Join(townRoot, rig, ".beads"), 0o755)
+		os.WriteFile(filepath.Join(townRoot, rig, ".beads", "beads.db"), nil, 0o644)
+	}
+	prefixMap = buildPrefixMap()
+
+	fakeBin(t, "bd", `case "$BEADS_DIR" in
+*bad*) echo "database is locked" >&2; exit 1 ;;
+*) echo '[]' ;;
+esac
+`)
+
+	w := httptest.NewRecorder()
+	handleRigs(w, httptest.NewRequest("GET", "/api/rigs", nil))
+	var rigs []rigOverview
+	if err := json.Unmarshal(w.Body.Bytes(), &rigs); err != nil {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	want := map[string]string{"good": "ok", "bad": "broken", "gone": "broken", "town": "degraded"}
+	for _, rig := range rigs {
+		if rig.Health == nil {
+			t.Errorf("%s: no health", rig.Name)
+			continue
+		}
+		if rig.Health.Status != want[rig.Name] {
+			t.Errorf("%s: health %+v, want %s", rig.Name, rig.Health, want[rig.Name])
+		}
+		if rig.Health.Status != "ok" && rig.Health.Reason == "" {
+			t.Errorf("%s: %s without a reason", rig.Name, rig.Health.Status)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleRigs(w, httptest.NewRequest("GET", "/api/rigs?probe=0", nil))
+	rigs = nil
+	json.Unmarshal(w.Body.Bytes(), &rigs)
+	for _, rig := range rigs {
+		if rig.Health != nil {
+			t.Errorf("probe=0: %s has health %+v", rig.Name, rig.Health)
+		}
+	}
+}
+
+func TestDebounceHealth(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".beads")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	broken := &rigHealth{"broken", "beads directory missing"}
+
+	debounceHealth(dir, &rigHealth{Status: "degraded", Reason: "no beads.db"}, time.Minute, start)
+	if h := debounceHealth(dir, broken, time.Minute, start.Add(time.Second)); h.Status != "degraded" || h.Reason == "" {
+		t.Errorf("first failure within grace = %+v, want the last status held", h)
+	}
+	if h := debounceHealth(dir, broken, time.Minute, start.Add(30*time.Second)); h.Status != "degraded" {
+		t.Errorf("still within grace = %+v", h)
+	}
+	if h := debounceHealth(dir, broken, time.Minute, start.Add(62*time.Second)); h.Status != "broken" {
+		t.Errorf("past grace = %+v, want broken", h)
+	}
+
+	// Recovery resets the clock.
+	debounceHealth(dir, &rigHealth{Status: "ok"}, time.Minute, start.Add(70*time.Second))
+	if h := debounceHealth(dir, broken, time.Minute, start.Add(80*time.Second)); h.Status != "ok" {
+		t.Errorf("blip after recovery = %+v, want ok", h)
+	}
+
+	if h := debounceHealth(filepath.Join(t.TempDir(), "other"), broken, 0, start); h.Status != "broken" {
+		t.Errorf("zero grace = %+v, want broken at once", h)
+	}
+}
+
+func TestAlwaysShowRigs(t *testing.T) {
+	origRoot, origMap, origPath := townRoot, prefixMap, configPath
+	defer func() { townRoot, prefixMap, configPath = origRoot, origMap, origPath }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	os.MkdirAll(filepath.Join(townRoot, ".beads"), 0o755)
+	os.WriteFile(filepath.Join(townRoot, ".beads", "routes.jsonl"), []byte(`{"prefix":"hq-","path":"."}
+{"prefix":"ok-","path":"good"}
+{"prefix":"old-","path":"retired","enabled":false}
+`), 0o644)
+	prefixMap = buildPrefixMap()
+	fakeBin(t, "bd", `echo '[]'`)
+
+	cfg := loadConfig()
+	cfg.AlwaysShowRigs = []string{"ok", "nw-", "old"}
+	saveConfig(cfg)
+
+	w := httptest.NewRecorder()
+	handleRigs(w, httptest.NewRequest("GET", "/api/rigs?probe=0", nil))
+	var rigs []rigOverview
+	if err := json.Unmarshal(w.Body.Bytes(), &rigs); err != nil {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	byName := map[string]rigOverview{}
+	for _, rig := range rigs {
+		byName[rig.Name] = rig
+	}
+	if len(rigs) != 3 || byName["good"].Placeholder {
+		t.Errorf("rigs = %+v, want town, good and one placeholder", rigs)
+	}
+	if nw, ok := byName["nw"]; !ok || !nw.Placeholder || nw.OpenCount != 0 {
+		t.Errorf("always-shown empty rig nw = %+v, %v, want a placeholder with count 0", nw, ok)
+	}
+	if _, ok := byName["old"]; ok {
+		t.Error("a disabled route must stay hidden even when always shown")
+	}
+
+	w = httptest.NewRecorder()
+	handleOverviewRigs(w, httptest.NewRequest("GET", "/api/overview/rigs", nil))
+	var counts []rigCount
+	json.Unmarshal(w.Body.Bytes(), &counts)
+	found := false
+	for _, c := range counts {
+		if c.Prefix == "nw" {
+			found = c.Total == 0
+		}
+	}
+	if !found {
+		t.Errorf("overview/rigs = %+v, want nw with total 0", counts)
+	}
+}
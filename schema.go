@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// bdSchema is what rigradar learned at startup about the bead JSON the
+// installed bd prints, reported in /health and /api/diagnostics. It is a
+// hint only: one sample can't speak for every rig (or a database halfway
+// through a migration), so parsing always accepts every known form.
+// Empty fields mean detection was inconclusive.
+type bdSchema struct {
+	BdVersion string `json:"bdVersion,omitempty"`
+	// Priority is "int" (2) or "string" ("P2" or "2"), taken from a
+	// sample bead.
+	Priority string `json:"priority,omitempty"`
+}
+
+var detectedSchema atomic.Pointer[bdSchema]
+
+// currentSchema returns the detected schema, or the zero (lenient) schema
+// before detection has finished.
+func currentSchema() bdSchema {
+	if s := detectedSchema.Load(); s != nil {
+		return *s
+	}
+	return bdSchema{}
+}
+
+// detectSchema reads bd's version and looks at the first bead `bd list`
+// returns in any beads directory to see how fields are encoded.
+func detectSchema(ctx context.Context) bdSchema {
+	s := bdSchema{BdVersion: toolVersions()["bd"].Version}
+	for _, dir := range beadDirs() {
+		data, err := execCmdContext(ctx, "bd", []string{"list", "--limit=1", "--json"}, map[string]string{"BEADS_DIR": dir})
+		if err != nil {
+			continue
+		}
+		// Wrapped output ({"issues": [...]}) is unwrapped as for listings.
+		beads, err := decodeBeadList(data)
+		if err != nil || len(beads) == 0 {
+			continue
+		}
+		var bead map[string]json.RawMessage
+		if json.Unmarshal(beads[0], &bead) != nil {
+			continue
+		}
+		if p := bead["priority"]; len(p) > 0 {
+			switch {
+			case p[0] == '"':
+				s.Priority = "string"
+			case p[0] == '-' || (p[0] >= '0' && p[0] <= '9'):
+				s.Priority = "int"
+			}
+		}
+		break
+	}
+	return s
+}
+
+// startSchemaDetection runs detectSchema in the background.
+func startSchemaDetection(ctx context.Context) {
+	go func() {
+		s := detectSchema(ctx)
+		detectedSchema.Store(&s)
+		if s.Priority == "" {
+			log.Printf("bd schema: version %q, priority encoding unknown", s.BdVersion)
+			return
+		}
+		log.Printf("bd schema: version %q, priority as %s", s.BdVersion, s.Priority)
+	}()
+}
+
+// beadPriority reads a bead's priority as decoded from JSON, either a
+// number (2) or a string ("P2" or "2"), whatever the detected schema says.
+func beadPriority(v any) (int, bool) {
+	switch p := v.(type) {
+	case float64:
+		return int(p), true
+	case string:
+		p = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(p)), "P")
+		if n, err := strconv.Atoi(p); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectSchema(t *testing.T) {
+	origMap := prefixMap
+	defer func() { prefixMap = origMap }()
+	root := t.TempDir()
+	prefixMap = map[string]string{
+		"aa": filepath.Join(root, "a", ".beads"),
+		"bb": filepath.Join(root, "b", ".beads"),
+	}
+	invalidateToolCaches()
+	t.Cleanup(invalidateToolCaches)
+	// The first rig is empty; the sample comes from the second.
+	fakeBin(t, "bd", `case "$1" in
+--version) echo "bd version 0.50.1" ;;
+*) case "$BEADS_DIR" in */a/*) echo '[]' ;; *) echo '[{"id":"b-1","priority":"P1"}]' ;; esac ;;
+esac`)
+
+	s := detectSchema(context.Background())
+	if s.BdVersion != "bd version 0.50.1" || s.Priority != "string" {
+		t.Errorf("detectSchema = %+v, want bd 0.50.1 with string priorities", s)
+	}
+
+	// Wrapped list output is unwrapped like /api/beads does.
+	fakeBin(t, "bd", `case "$1" in
+--version) echo "bd version 0.50.1" ;;
+*) echo '{"data":{"beads":[{"id":"a-1","priority":2}]}}' ;;
+esac`)
+	if s := detectSchema(context.Background()); s.Priority != "int" {
+		t.Errorf("wrapped output: detectSchema = %+v, want int priorities", s)
+	}
+}
+
+func TestBeadPriority(t *testing.T) {
+	defer detectedSchema.Store(nil)
+	cases := []struct {
+		form string
+		in   any
+		want int
+		ok   bool
+	}{
+		{"", 2.0, 2, true},
+		{"", "P3", 3, true},
+		{"", "1", 1, true},
+		{"", "high", 0, false},
+		{"int", 2.0, 2, true},
+		{"int", "P3", 3, true},
+		{"string", "p0", 0, true},
+		{"string", 2.0, 2, true},
+		{"", nil, 0, false},
+	}
+	for _, c := range cases {
+		detectedSchema.Store(&bdSchema{Priority: c.form})
+		if got, ok := beadPriority(c.in); got != c.want || ok != c.ok {
+			t.Errorf("schema %q: beadPriority(%v) = %d, %v, want %d, %v", c.form, c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestComputeStatsStringPriority(t *testing.T) {
+	// A sample said int, but the other encoding still counts.
+	detectedSchema.Store(&bdSchema{Priority: "int"})
+	defer detectedSchema.Store(nil)
+	beads := []json.RawMessage{
+		json.RawMessage(`{"status":"open","priority":"P0"}`),
+		json.RawMessage(`{"status":"open","priority":1}`),
+	}
+	stats := computeStats(beads, Config{})
+	if stats.Open != 2 || stats.OpenByPriority["P0"] != 1 || stats.OpenByPriority["P1"] != 1 {
+		t.Errorf("stats = %+v, want both encodings counted", stats)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// beadStats counts the town's beads. Priority counts cover open work
+// only (anything not closed).
+type beadStats struct {
+	Total          int            `json:"total"`
+	Open           int            `json:"open"`
+	ByStatus       map[string]int `json:"byStatus"`
+	ByType         map[string]int `json:"byType"`
+	OpenByPriority map[string]int `json:"openByPriority"`
+	Alerts         []beadAlert    `json:"alerts"`
+}
+
+// beadAlert reports a priority level whose open count is above its
+// configured threshold.
+type beadAlert struct {
+	Level     string `json:"level"`
+	Priority  int    `json:"priority"`
+	Open      int    `json:"open"`
+	Threshold int    `json:"threshold"`
+	Message   string `json:"message"`
+}
+
+func computeStats(beads []json.RawMessage, cfg Config) beadStats {
+	stats := beadStats{
+		ByStatus:       map[string]int{},
+		ByType:         map[string]int{},
+		OpenByPriority: map[string]int{},
+	}
+	for _, raw := range beads {
+		var b struct {
+			Status    string `json:"status"`
+			IssueType string `json:"issue_type"`
+			Priority  any    `json:"priority"`
+		}
+		if json.Unmarshal(raw, &b) != nil {
+			continue
+		}
+		stats.Total++
+		stats.ByStatus[b.Status]++
+		stats.ByType[b.IssueType]++
+		if b.Status == "closed" {
+			continue
+		}
+		stats.Open++
+		if p, ok := beadPriority(b.Priority); ok {
+			stats.OpenByPriority["P"+strconv.Itoa(p)]++
+		}
+	}
+	stats.Alerts = priorityAlerts(stats.OpenByPriority, alertThresholds(cfg))
+	return stats
+}
+
+// alertThresholds merges Config.AlertThresholds with the P0AlertThreshold
+// shorthand. Zero or negative thresholds are off.
+func alertThresholds(cfg Config) map[int]int {
+	out := make(map[int]int)
+	if cfg.P0AlertThreshold > 0 {
+		out[0] = cfg.P0AlertThreshold
+	}
+	for p, n := range cfg.AlertThresholds {
+		if n > 0 {
+			out[p] = n
+		} else {
+			delete(out, p)
+		}
+	}
+	return out
+}
+
+func priorityAlerts(openByPriority map[string]int, thresholds map[int]int) []beadAlert {
+	alerts := []beadAlert{}
+	for p, limit := range thresholds {
+		level := "P" + strconv.Itoa(p)
+		if n := openByPriority[level]; n > limit {
+			alerts = append(alerts, beadAlert{
+				Level:     level,
+				Priority:  p,
+				Open:      n,
+				Threshold: limit,
+				Message:   fmt.Sprintf("%d open %s beads (threshold %d)", n, level, limit),
+			})
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Priority < alerts[j].Priority })
+	return alerts
+}
+
+// loadStats fetches every bead across the rigs and aggregates them.
+func loadStats(ctx context.Context, cfg Config) (beadStats, bool) {
+	beads, partial := loadAllBeads(ctx, cfg)
+	return computeStats(beads, cfg), partial
+}
+
+// loadAllBeads runs the unfiltered fan-out the aggregate endpoints count
+// over, through the beads cache.
+func loadAllBeads(ctx context.Context, cfg Config) ([]json.RawMessage, bool) {
+	ctx, cancel := withBeadsBudget(ctx)
+	defer cancel()
+	dirs, _ := limitDirs(beadDirs(), cfg.MaxRigs)
+	beads, _, partial := fetchBeads(ctx, dirs, nil, beadsTTL(cfg))
+	return beads, partial
+}
+
+// rigCount is one rig's bar in /api/overview/rigs. Total also counts
+// statuses other than the three broken out.
+type rigCount struct {
+	Prefix     string `json:"prefix"`
+	Name       string `json:"name"`
+	Open       int    `json:"open"`
+	InProgress int    `json:"inProgress"`
+	Closed     int    `json:"closed"`
+	Total      int    `json:"total"`
+}
+
+// countByRig tallies beads by id prefix, largest rig first. The town (hq)
+// and the always-shown prefixes are listed even when they have no beads.
+func countByRig(beads []json.RawMessage, rigNames map[string]string, always []string) []rigCount {
+	byPrefix := map[string]*rigCount{"hq": {Prefix: "hq", Name: "town"}}
+	for _, p := range always {
+		if byPrefix[p] == nil {
+			byPrefix[p] = &rigCount{Prefix: p, Name: beadRig(p+"-", rigNames)}
+		}
+	}
+	for _, raw := range beads {
+		var b struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		}
+		if json.Unmarshal(raw, &b) != nil {
+			continue
+		}
+		prefix, _, _ := strings.Cut(b.ID, "-")
+		c, ok := byPrefix[prefix]
+		if !ok {
+			c = &rigCount{Prefix: prefix, Name: beadRig(b.ID, rigNames)}
+			byPrefix[prefix] = c
+		}
+		c.Total++
+		switch b.Status {
+		case "open":
+			c.Open++
+		case "in_progress":
+			c.InProgress++
+		case "closed":
+			c.Closed++
+		}
+	}
+	out := make([]rigCount, 0, len(byPrefix))
+	for _, c := range byPrefix {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Prefix < out[j].Prefix
+	})
+	return out
+}
+
+// handleOverviewRigs serves per-rig bead totals for the overview bars.
+func handleOverviewRigs(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	beads, partial := loadAllBeads(r.Context(), cfg)
+	if partial {
+		w.Header().Set("X-Rigradar-Partial", "true")
+	}
+	sendJSON(w, countByRig(beads, buildRigPrefixNameMap(), alwaysShownPrefixes(cfg)), http.StatusOK)
+}
+
+// priorityMatrix is /api/matrix: bead counts by priority (rows) and
+// status (columns), with the totals of each.
+type priorityMatrix struct {
+	Matrix         map[string]map[string]int `json:"matrix"`
+	PriorityTotals map[string]int            `json:"priorityTotals"`
+	StatusTotals   map[string]int            `json:"statusTotals"`
+	Total          int                       `json:"total"`
+}
+
+// computeMatrix tallies beads into a priorityMatrix. Priorities are "P0"
+// etc.; a missing or unreadable priority, and a missing status, count as
+// "unknown".
+func computeMatrix(beads []json.RawMessage) priorityMatrix {
+	m := priorityMatrix{
+		Matrix:         map[string]map[string]int{},
+		PriorityTotals: map[string]int{},
+		StatusTotals:   map[string]int{},
+	}
+	for _, raw := range beads {
+		var b struct {
+			Status   string `json:"status"`
+			Priority any    `json:"priority"`
+		}
+		if json.Unmarshal(raw, &b) != nil {
+			continue
+		}
+		row := "unknown"
+		if p, ok := beadPriority(b.Priority); ok {
+			row = "P" + strconv.Itoa(p)
+		}
+		col := b.Status
+		if col == "" {
+			col = "unknown"
+		}
+		if m.Matrix[row] == nil {
+			m.Matrix[row] = map[string]int{}
+		}
+		m.Matrix[row][col]++
+		m.PriorityTotals[row]++
+		m.StatusTotals[col]++
+		m.Total++
+	}
+	return m
+}
+
+// handleMatrix serves the priority × status counts behind a triage
+// heatmap, town-wide or for the rig named by ?rig= (a prefix or rig name).
+func handleMatrix(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+
+	dirs, _ := limitDirs(beadDirs(), cfg.MaxRigs)
+	if rig := r.URL.Query().Get("rig"); rig != "" {
+		dir, ok := rigBeadsDir(rig)
+		if !ok {
+			sendError(w, fmt.Sprintf("unknown rig %q", rig), http.StatusNotFound)
+			return
+		}
+		dirs = []string{dir}
+	}
+	ctx, cancel := withBeadsBudget(r.Context())
+	defer cancel()
+	beads, _, partial := fetchBeads(ctx, dirs, nil, beadsTTL(cfg))
+	if partial {
+		w.Header().Set("X-Rigradar-Partial", "true")
+	}
+	sendJSON(w, computeMatrix(beads), http.StatusOK)
+}
+
+// handleStats serves bead counts by status, type and open priority, plus
+// any priority alerts.
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	stats, partial := loadStats(r.Context(), cfg)
+	if partial {
+		w.Header().Set("X-Rigradar-Partial", "true")
+	}
+	sendJSON(w, stats, http.StatusOK)
+}
+
+// handleAlerts serves just the alerts from /api/stats, [] when no
+// threshold is breached.
+func handleAlerts(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+	stats, partial := loadStats(r.Context(), cfg)
+	if partial {
+		w.Header().Set("X-Rigradar-Partial", "true")
+	}
+	sendJSON(w, stats.Alerts, http.StatusOK)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAlertsThreshold(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+	fakeBin(t, "bd", `echo '[
+ {"id":"ri-1","status":"open","priority":0,"issue_type":"bug"},
+ {"id":"ri-2","status":"in_progress","priority":0,"issue_type":"bug"},
+ {"id":"ri-3","status":"closed","priority":0,"issue_type":"bug"},
+ {"id":"ri-4","status":"open","priority":2,"issue_type":"task"}
+]'`)
+
+	alerts := func() []beadAlert {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleAlerts(w, httptest.NewRequest("GET", "/api/alerts", nil))
+		var out []beadAlert
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil || out == nil {
+			t.Fatalf("alerts body %s: %v", w.Body, err)
+		}
+		return out
+	}
+
+	cfg := loadConfig()
+	cfg.P0AlertThreshold = 2
+	saveConfig(cfg)
+	if got := alerts(); len(got) != 0 {
+		t.Errorf("2 open P0 at threshold 2: alerts = %v, want none", got)
+	}
+
+	cfg.P0AlertThreshold = 1
+	cfg.AlertThresholds = map[int]int{2: 5}
+	saveConfig(cfg)
+	got := alerts()
+	if len(got) != 1 || got[0].Level != "P0" || got[0].Open != 2 || got[0].Threshold != 1 {
+		t.Errorf("alerts = %+v, want one P0 alert with 2 open", got)
+	}
+
+	w := httptest.NewRecorder()
+	handleStats(w, httptest.NewRequest("GET", "/api/stats", nil))
+	var stats beadStats
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.Total != 4 || stats.Open != 3 || stats.OpenByPriority["P0"] != 2 || stats.ByType["bug"] != 3 || len(stats.Alerts) != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestOverviewRigs(t *testing.T) {
+	origPath, origMap, origRoot := configPath, prefixMap, townRoot
+	defer func() { configPath, prefixMap, townRoot = origPath, origMap, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	os.MkdirAll(filepath.Join(townRoot, ".beads"), 0o755)
+	os.WriteFile(filepath.Join(townRoot, ".beads", "routes.jsonl"), []byte(`{"prefix":"ri-","path":"rigradar"}`+"\n"), 0o644)
+	prefixMap = map[string]string{
+		"hq": filepath.Join(townRoot, ".beads"),
+		"ri": filepath.Join(townRoot, "rigradar", ".beads"),
+		"gt": filepath.Join(townRoot, "gastown", ".beads"),
+	}
+	fakeBin(t, "bd", `case "$BEADS_DIR" in
+*/rigradar/*) echo '[{"id":"ri-1","status":"open"},{"id":"ri-2","status":"in_progress"},{"id":"ri-3","status":"closed"},{"id":"ri-4","status":"blocked"}]' ;;
+*/gastown/*) echo '[{"id":"gt-1","status":"open"},{"id":"gt-2","status":"open"}]' ;;
+*) echo '[]' ;;
+esac`)
+
+	w := httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads", nil))
+	var beads []struct{ ID, Status string }
+	json.Unmarshal(w.Body.Bytes(), &beads)
+	tally := map[string]map[string]int{"hq": {}}
+	for _, b := range beads {
+		prefix, _, _ := strings.Cut(b.ID, "-")
+		if tally[prefix] == nil {
+			tally[prefix] = map[string]int{}
+		}
+		tally[prefix][b.Status]++
+		tally[prefix]["total"]++
+	}
+
+	w = httptest.NewRecorder()
+	handleOverviewRigs(w, httptest.NewRequest("GET", "/api/overview/rigs", nil))
+	var got []rigCount
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("overview/rigs body %s: %v", w.Body, err)
+	}
+	if len(got) != len(tally) {
+		t.Fatalf("overview/rigs = %+v, want one entry per rig in %v", got, tally)
+	}
+	for _, c := range got {
+		want := tally[c.Prefix]
+		if c.Open != want["open"] || c.InProgress != want["in_progress"] || c.Closed != want["closed"] || c.Total != want["total"] {
+			t.Errorf("%s = %+v, want %v", c.Prefix, c, want)
+		}
+	}
+	if got[0].Prefix != "ri" || got[0].Name != "rigradar" || got[len(got)-1].Name != "town" {
+		t.Errorf("order = %+v, want rigradar (largest) first and the empty town last", got)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	root := t.TempDir()
+	prefixMap = map[string]string{
+		"ri": filepath.Join(root, "rigradar", ".beads"),
+		"gt": filepath.Join(root, "gastown", ".beads"),
+	}
+	fakeBin(t, "bd", `case "$BEADS_DIR" in
+*rigradar*) echo '[
+ {"id":"ri-1","status":"open","priority":0},
+ {"id":"ri-2","status":"open","priority":"P0"},
+ {"id":"ri-3","status":"closed","priority":2}
+]' ;;
+*) echo '[
+ {"id":"gt-1","status":"in_progress","priority":0},
+ {"id":"gt-2","status":"open"}
+]' ;;
+esac`)
+
+	get := func(query string) priorityMatrix {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleMatrix(w, httptest.NewRequest("GET", "/api/matrix"+query, nil))
+		var m priorityMatrix
+		if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil || w.Code != 200 {
+			t.Fatalf("matrix%s = %d %s", query, w.Code, w.Body)
+		}
+		return m
+	}
+
+	m := get("")
+	want := map[string]map[string]int{
+		"P0":      {"open": 2, "in_progress": 1},
+		"P2":      {"closed": 1},
+		"unknown": {"open": 1},
+	}
+	if len(m.Matrix) != len(want) {
+		t.Fatalf("matrix = %v, want %v", m.Matrix, want)
+	}
+	for row, cols := range want {
+		for col, n := range cols {
+			if m.Matrix[row][col] != n {
+				t.Errorf("matrix[%s][%s] = %d, want %d", row, col, m.Matrix[row][col], n)
+			}
+		}
+	}
+	if m.PriorityTotals["P0"] != 3 || m.PriorityTotals["unknown"] != 1 || m.StatusTotals["open"] != 3 || m.StatusTotals["closed"] != 1 || m.Total != 5 {
+		t.Errorf("totals = %v / %v / %d", m.PriorityTotals, m.StatusTotals, m.Total)
+	}
+
+	if m := get("?rig=gt"); m.Total != 2 || m.Matrix["P0"]["in_progress"] != 1 {
+		t.Errorf("rig=gt matrix = %+v, want only gastown's 2 beads", m)
+	}
+	w := httptest.NewRecorder()
+	handleMatrix(w, httptest.NewRequest("GET", "/api/matrix?rig=nope", nil))
+	if w.Code != 404 {
+		t.Errorf("unknown rig status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// beadLabels returns a bead's labels. bd calls them "labels"; "tags" is
+// accepted too. Beads with neither have none.
+func beadLabels(bead map[string]any) []string {
+	var out []string
+	for _, key := range []string{"labels", "tags"} {
+		list, _ := bead[key].([]any)
+		for _, v := range list {
+			if s, ok := v.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// filterByLabel keeps beads carrying label.
+func filterByLabel(beads []json.RawMessage, label string) []json.RawMessage {
+	out := []json.RawMessage{}
+	for _, raw := range beads {
+		var bead map[string]any
+		if json.Unmarshal(raw, &bead) != nil {
+			continue
+		}
+		for _, l := range beadLabels(bead) {
+			if l == label {
+				out = append(out, raw)
+				break
+			}
+		}
+	}
+	return out
+}
+
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// countLabels tallies labels across beads, most used first and ties by
+// name.
+func countLabels(beads []json.RawMessage) []tagCount {
+	counts := make(map[string]int)
+	for _, raw := range beads {
+		var bead map[string]any
+		if json.Unmarshal(raw, &bead) != nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, l := range beadLabels(bead) {
+			if !seen[l] {
+				seen[l] = true
+				counts[l]++
+			}
+		}
+	}
+	out := make([]tagCount, 0, len(counts))
+	for tag, n := range counts {
+		out = append(out, tagCount{tag, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Tag < out[j].Tag
+	})
+	return out
+}
+
+// handleTags lists every label in use across the rigs with how many beads
+// carry it. ?status= scopes the beads counted.
+func handleTags(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	cfg := loadConfig()
+	configMu.RUnlock()
+
+	var args []string
+	if status := r.URL.Query().Get("status"); status != "" {
+		args = append(args, "--status="+status)
+	}
+	ctx, cancel := withBeadsBudget(r.Context())
+	defer cancel()
+	dirs, _ := limitDirs(beadDirs(), cfg.MaxRigs)
+	beads, _, partial := fetchBeads(ctx, dirs, args, beadsTTL(cfg))
+	if partial {
+		w.Header().Set("X-Rigradar-Partial", "true")
+	}
+	sendJSON(w, countLabels(beads), http.StatusOK)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestTagsAndTagFilter(t *testing.T) {
+	origPath, origMap := configPath, prefixMap
+	defer func() { configPath, prefixMap = origPath, origMap }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	prefixMap = map[string]string{"ri": filepath.Join(t.TempDir(), ".beads")}
+
+	fakeBin(t, "bd", `echo '[
+ {"id":"ri-1","labels":["ui","bug"]},
+ {"id":"ri-2","labels":["ui"]},
+ {"id":"ri-3","tags":["ops","ops"]},
+ {"id":"ri-4","labels":null},
+ {"id":"ri-5"}
+]'`)
+
+	w := httptest.NewRecorder()
+	handleTags(w, httptest.NewRequest("GET", "/api/tags", nil))
+	var tags []tagCount
+	if err := json.Unmarshal(w.Body.Bytes(), &tags); err != nil {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	want := []tagCount{{"ui", 2}, {"bug", 1}, {"ops", 1}}
+	if len(tags) != len(want) {
+		t.Fatalf("tags = %v, want %v", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Errorf("tags[%d] = %v, want %v", i, tags[i], want[i])
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleBeads(w, httptest.NewRequest("GET", "/api/beads?tag=ui", nil))
+	var beads []struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &beads)
+	if len(beads) != 2 || beads[0].ID != "ri-1" || beads[1].ID != "ri-2" {
+		t.Errorf("tag=ui beads = %v", beads)
+	}
+}
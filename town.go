@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// townNameKeys are the keys a town's name has been stored under.
+var townNameKeys = []string{"name", "townName", "town_name", "town"}
+
+var townNameCache struct {
+	mu   sync.Mutex
+	root string
+	name string
+}
+
+// townName returns the town's human name. It is looked up once per town
+// root, in order: a .gastown marker file (JSON or a bare name), the mayor
+// config, then a metadata line in routes.jsonl, falling back to the base
+// name of townRoot.
+func townName() string {
+	townNameCache.mu.Lock()
+	defer townNameCache.mu.Unlock()
+	if townNameCache.root == townRoot && townNameCache.name != "" {
+		return townNameCache.name
+	}
+	name := lookupTownName(townRoot)
+	townNameCache.root, townNameCache.name = townRoot, name
+	return name
+}
+
+func lookupTownName(root string) string {
+	if data, err := os.ReadFile(filepath.Join(root, ".gastown")); err == nil {
+		if name := nameFromJSON(data); name != "" {
+			return name
+		}
+		// A plain-text marker holds just the name.
+		if line, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n"); line != "" && !strings.HasPrefix(line, "{") {
+			return strings.TrimSpace(line)
+		}
+	}
+	for _, p := range []string{
+		filepath.Join(root, ".gastown", "config.json"),
+		filepath.Join(root, "mayor", "config.json"),
+		filepath.Join(root, "mayor", "town.json"),
+	} {
+		if data, err := os.ReadFile(p); err == nil {
+			if name := nameFromJSON(data); name != "" {
+				return name
+			}
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, ".beads", "routes.jsonl")); err == nil {
+		sc := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+		for sc.Scan() {
+			var line map[string]any
+			// Route lines carry a prefix; a metadata line does not.
+			if json.Unmarshal(sc.Bytes(), &line) != nil || line["prefix"] != nil {
+				continue
+			}
+			if name := nameFromMap(line); name != "" {
+				return name
+			}
+		}
+	}
+	return filepath.Base(root)
+}
+
+func nameFromJSON(data []byte) string {
+	var fields map[string]any
+	if json.Unmarshal(data, &fields) != nil {
+		return ""
+	}
+	return nameFromMap(fields)
+}
+
+func nameFromMap(fields map[string]any) string {
+	for _, key := range townNameKeys {
+		if s, ok := fields[key].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
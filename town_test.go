@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupTownName(t *testing.T) {
+	write := func(t *testing.T, path, data string) {
+		t.Helper()
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root := filepath.Join(t.TempDir(), "gastown")
+	os.MkdirAll(root, 0o755)
+	if got := lookupTownName(root); got != "gastown" {
+		t.Errorf("no metadata: got %q, want the directory name", got)
+	}
+
+	write(t, filepath.Join(root, ".beads", "routes.jsonl"), `{"prefix":"ri-","path":"rigradar"}
+{"townName":"Routes Town"}
+`)
+	if got := lookupTownName(root); got != "Routes Town" {
+		t.Errorf("routes metadata: got %q", got)
+	}
+
+	write(t, filepath.Join(root, "mayor", "config.json"), `{"name":"Mayor Town"}`)
+	if got := lookupTownName(root); got != "Mayor Town" {
+		t.Errorf("mayor config: got %q", got)
+	}
+
+	write(t, filepath.Join(root, ".gastown"), "Marker Town\n")
+	if got := lookupTownName(root); got != "Marker Town" {
+		t.Errorf("plain .gastown marker: got %q", got)
+	}
+	write(t, filepath.Join(root, ".gastown"), `{"town":"JSON Town"}`)
+	if got := lookupTownName(root); got != "JSON Town" {
+		t.Errorf("JSON .gastown marker: got %q", got)
+	}
+}
+
+func TestTownNameCachedPerRoot(t *testing.T) {
+	origRoot := townRoot
+	defer func() { townRoot = origRoot }()
+	townRoot = filepath.Join(t.TempDir(), "alpha")
+	os.MkdirAll(townRoot, 0o755)
+	if got := townName(); got != "alpha" {
+		t.Fatalf("townName = %q, want alpha", got)
+	}
+	os.WriteFile(filepath.Join(townRoot, ".gastown"), []byte("Renamed"), 0o644)
+	if got := townName(); got != "alpha" {
+		t.Errorf("townName = %q after the marker changed, want the cached alpha", got)
+	}
+}
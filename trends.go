@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// trendSample is one point of the open-bead trend line.
+type trendSample struct {
+	Time time.Time `json:"time"`
+	Open int       `json:"open"`
+}
+
+// trendBuffer holds at most size of the most recent samples, oldest
+// first. It lives only in memory, so the history starts over on restart.
+type trendBuffer struct {
+	mu      sync.Mutex
+	samples []trendSample
+	size    int
+}
+
+func newTrendBuffer(size int) *trendBuffer {
+	if size <= 0 {
+		size = 1
+	}
+	return &trendBuffer{samples: make([]trendSample, 0, size), size: size}
+}
+
+func (b *trendBuffer) add(s trendSample) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.samples) == b.size {
+		// Shift in place so the backing array never grows.
+		b.samples = b.samples[:copy(b.samples, b.samples[1:])]
+	}
+	b.samples = append(b.samples, s)
+}
+
+// prune drops samples taken before cutoff and reports how many went.
+func (b *trendBuffer) prune(cutoff time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for n < len(b.samples) && b.samples[n].Time.Before(cutoff) {
+		n++
+	}
+	b.samples = b.samples[:copy(b.samples, b.samples[n:])]
+	return n
+}
+
+// snapshot returns the buffered samples, oldest first.
+func (b *trendBuffer) snapshot() []trendSample {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]trendSample{}, b.samples...)
+}
+
+var (
+	trends        = newTrendBuffer(120)
+	trendInterval time.Duration
+)
+
+// trendSettings resolves the sampler interval and buffer size from config.
+// The interval falls back to the UI refresh interval; the size is
+// HistoryMaxEntries, else the older TrendBufferSize, else 120.
+func trendSettings(cfg Config) (time.Duration, int) {
+	ms := cfg.TrendSampleMs
+	if ms <= 0 {
+		ms = cfg.RefreshInterval
+	}
+	if ms <= 0 {
+		ms = 30000
+	}
+	size := cfg.HistoryMaxEntries
+	if size <= 0 {
+		size = cfg.TrendBufferSize
+	}
+	if size <= 0 {
+		size = 120
+	}
+	return time.Duration(ms) * time.Millisecond, size
+}
+
+// historyRetention is how long in-memory history is kept:
+// HistoryRetentionMinutes, default a day.
+func historyRetention(cfg Config) time.Duration {
+	if cfg.HistoryRetentionMinutes > 0 {
+		return time.Duration(cfg.HistoryRetentionMinutes) * time.Minute
+	}
+	return 24 * time.Hour
+}
+
+// startHistoryPruner drops history older than retention once a minute
+// (or every retention, if shorter) until ctx is done. The trend buffer is
+// the only in-memory history today; new ones should be pruned here too.
+func startHistoryPruner(ctx context.Context, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(min(retention, time.Minute))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				trends.prune(now.Add(-retention))
+			}
+		}
+	}()
+}
+
+// startTrendSampler records the town-wide open bead count every interval
+// until ctx is done.
+func startTrendSampler(ctx context.Context, interval time.Duration) {
+	trendInterval = interval
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			sampleTrend(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func sampleTrend(parent context.Context) {
+	ctx, cancel := withBeadsBudget(parent)
+	defer cancel()
+	beads, errs, partial := collectBeads(ctx, beadDirs(), []string{"--status=open"})
+	if partial || (len(beads) == 0 && len(errs) > 0) {
+		log.Printf("trends: skipping sample (partial=%v, %d rig errors)", partial, len(errs))
+		return
+	}
+	trends.add(trendSample{Time: time.Now(), Open: len(beads)})
+}
+
+func handleTrends(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, map[string]any{
+		"intervalMs": trendInterval.Milliseconds(),
+		"samples":    trends.snapshot(),
+	}, http.StatusOK)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrendBufferWraps(t *testing.T) {
+	b := newTrendBuffer(3)
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		b.add(trendSample{Time: base.Add(time.Duration(i) * time.Second), Open: i})
+	}
+
+	got := b.snapshot()
+	if len(got) != 3 {
+		t.Fatalf("snapshot len = %d, want 3", len(got))
+	}
+	for i, want := range []int{2, 3, 4} {
+		if got[i].Open != want {
+			t.Errorf("snapshot[%d].Open = %d, want %d (oldest first)", i, got[i].Open, want)
+		}
+	}
+}
+
+func TestTrendSettingsDefaults(t *testing.T) {
+	interval, size := trendSettings(Config{RefreshInterval: 5000})
+	if interval != 5*time.Second {
+		t.Errorf("interval = %s, want refresh interval 5s", interval)
+	}
+	if size != 120 {
+		t.Errorf("size = %d, want 120", size)
+	}
+
+	interval, size = trendSettings(Config{RefreshInterval: 5000, TrendSampleMs: 1000, TrendBufferSize: 10})
+	if interval != time.Second || size != 10 {
+		t.Errorf("configured settings = %s/%d, want 1s/10", interval, size)
+	}
+}
+
+func TestTrendBufferPrune(t *testing.T) {
+	b := newTrendBuffer(10)
+	now := time.Now()
+	for _, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, 30 * time.Minute, time.Minute} {
+		b.add(trendSample{Time: now.Add(-age), Open: int(age.Minutes())})
+	}
+
+	if n := b.prune(now.Add(-time.Hour)); n != 2 {
+		t.Errorf("pruned %d samples, want the 2 older than an hour", n)
+	}
+	got := b.snapshot()
+	if len(got) != 2 || got[0].Open != 30 || got[1].Open != 1 {
+		t.Fatalf("after prune = %+v, want the 30m and 1m samples", got)
+	}
+
+	// The buffer still wraps at its size after pruning.
+	for i := 0; i < 12; i++ {
+		b.add(trendSample{Time: now, Open: 100 + i})
+	}
+	if got := b.snapshot(); len(got) != 10 || got[0].Open != 102 {
+		t.Errorf("after refill len = %d, first = %d, want 10 starting at 102", len(got), got[0].Open)
+	}
+}
+
+func TestHistorySettings(t *testing.T) {
+	if _, size := trendSettings(Config{TrendBufferSize: 10, HistoryMaxEntries: 50}); size != 50 {
+		t.Errorf("size = %d, want historyMaxEntries to win", size)
+	}
+	if got := historyRetention(Config{}); got != 24*time.Hour {
+		t.Errorf("default retention = %s, want 24h", got)
+	}
+	if got := historyRetention(Config{HistoryRetentionMinutes: 90}); got != 90*time.Minute {
+		t.Errorf("retention = %s, want 90m", got)
+	}
+}
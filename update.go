@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// updateBeadRequest is the body of POST /api/bead/{id}/update. Only the
+// fields present are changed. IfVersion and IfUpdatedAt make the update
+// conditional on the bead not having changed since the client read it.
+type updateBeadRequest struct {
+	Title       *string `json:"title"`
+	Status      *string `json:"status"`
+	Priority    *int    `json:"priority"`
+	Assignee    *string `json:"assignee"`
+	Description *string `json:"description"`
+
+	IfVersion   json.RawMessage `json:"ifVersion"`
+	IfUpdatedAt string          `json:"ifUpdatedAt"`
+}
+
+func (u updateBeadRequest) args(id string) []string {
+	args := []string{"update", id, "--json"}
+	if u.Title != nil {
+		args = append(args, "--title="+*u.Title)
+	}
+	if u.Status != nil {
+		args = append(args, "--status="+*u.Status)
+	}
+	if u.Priority != nil {
+		args = append(args, "--priority="+strconv.Itoa(*u.Priority))
+	}
+	if u.Assignee != nil {
+		args = append(args, "--assignee="+*u.Assignee)
+	}
+	if u.Description != nil {
+		args = append(args, "--description="+*u.Description)
+	}
+	return args
+}
+
+// staleReason compares the client's precondition with the current bead and
+// describes the mismatch, or returns "" when the bead is unchanged. bd's
+// "version" field is used when it has one; otherwise updated_at.
+func (u updateBeadRequest) staleReason(bead map[string]any) (string, error) {
+	if len(u.IfVersion) > 0 {
+		if current, ok := bead["version"]; ok {
+			var want any
+			if err := json.Unmarshal(u.IfVersion, &want); err != nil {
+				return "", fmt.Errorf("invalid ifVersion: %v", err)
+			}
+			if fmt.Sprint(want) != fmt.Sprint(current) {
+				return fmt.Sprintf("version is %v, not %v", current, want), nil
+			}
+			return "", nil
+		}
+		if u.IfUpdatedAt == "" {
+			return "", fmt.Errorf("bd reports no bead version; send ifUpdatedAt instead")
+		}
+	}
+	current, _ := bead["updated_at"].(string)
+	if sameTimestamp(current, u.IfUpdatedAt) {
+		return "", nil
+	}
+	return fmt.Sprintf("updated_at is %s, not %s", current, u.IfUpdatedAt), nil
+}
+
+// sameTimestamp compares RFC 3339 times as instants, so differing zone
+// offsets for the same moment still match.
+func sameTimestamp(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339Nano, a)
+	tb, errB := time.Parse(time.RFC3339Nano, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ta.Equal(tb)
+}
+
+// handleUpdateBead runs `bd update` with the fields in the body. With
+// ifVersion or ifUpdatedAt it first re-reads the bead and answers 409 with
+// the current bead if it changed in the meantime. bd has no atomic
+// compare-and-set, so this narrows the lost-update window rather than
+// closing it.
+func handleUpdateBead(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !validBeadID(id) {
+		sendError(w, fmt.Sprintf("invalid bead id %q", id), http.StatusBadRequest)
+		return
+	}
+	var body updateBeadRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	args := body.args(id)
+	if len(args) == 3 {
+		sendError(w, "no fields to update", http.StatusBadRequest)
+		return
+	}
+	if body.Title != nil && strings.TrimSpace(*body.Title) == "" {
+		sendError(w, "title cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	if len(body.IfVersion) > 0 || body.IfUpdatedAt != "" {
+		bead, err := showBead(r, id)
+		if err != nil {
+			sendCmdError(w, err, http.StatusInternalServerError)
+			return
+		}
+		stale, err := body.staleReason(bead)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if stale != "" {
+			sendErrorDetails(w, fmt.Sprintf("%s changed since it was read: %s", id, stale), http.StatusConflict, map[string]any{"current": bead})
+			return
+		}
+	}
+
+	// Like close, not tied to r.Context() once the write starts.
+	data, err := execCmd("bd", args, map[string]string{"BEADS_DIR": beadsDirForID(id)})
+	if err != nil {
+		sendCmdError(w, err, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, data, http.StatusOK)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateBeadConflict(t *testing.T) {
+	origMap, origRoot := prefixMap, townRoot
+	defer func() { prefixMap, townRoot = origMap, origRoot }()
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"ri": filepath.Join(townRoot, "rigradar", ".beads")}
+
+	updates := filepath.Join(t.TempDir(), "updates")
+	fakeBin(t, "bd", `case "$1" in
+show) echo '[{"id":"ri-1","title":"Current","updated_at":"2026-03-01T10:00:00Z"}]' ;;
+update) echo "$@" >> `+updates+`; echo '{"id":"ri-1"}' ;;
+esac
+`)
+	update := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/bead/ri-1/update", strings.NewReader(body))
+		req.SetPathValue("id", "ri-1")
+		w := httptest.NewRecorder()
+		handleUpdateBead(w, req)
+		return w
+	}
+
+	w := update(`{"title":"Mine","ifUpdatedAt":"2026-02-01T10:00:00Z"}`)
+	if w.Code != 409 {
+		t.Fatalf("stale update status = %d, want 409; body: %s", w.Code, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), `"title":"Current"`) {
+		t.Errorf("conflict body should carry the current bead: %s", w.Body)
+	}
+	if _, err := os.Stat(updates); err == nil {
+		t.Fatal("bd update ran for a stale update")
+	}
+
+	// Same instant, different offset.
+	w = update(`{"title":"Mine","priority":1,"ifUpdatedAt":"2026-03-01T11:00:00+01:00"}`)
+	if w.Code != 200 {
+		t.Fatalf("fresh update status = %d, body: %s", w.Code, w.Body)
+	}
+	got, _ := os.ReadFile(updates)
+	if want := "update ri-1 --json --title=Mine --priority=1"; strings.TrimSpace(string(got)) != want {
+		t.Errorf("bd args = %q, want %q", got, want)
+	}
+
+	// No version field in bd output and no timestamp to fall back on.
+	if w := update(`{"title":"Mine","ifVersion":3}`); w.Code != 400 {
+		t.Errorf("ifVersion without a bd version: status %d, want 400", w.Code)
+	}
+	if w := update(`{}`); w.Code != 400 {
+		t.Errorf("empty update status = %d, want 400", w.Code)
+	}
+}
+
+func TestUpdateBeadVersion(t *testing.T) {
+	u := updateBeadRequest{IfVersion: []byte("4")}
+	if stale, err := u.staleReason(map[string]any{"version": 4.0}); err != nil || stale != "" {
+		t.Errorf("matching version: %q, %v", stale, err)
+	}
+	if stale, _ := u.staleReason(map[string]any{"version": 5.0}); stale == "" {
+		t.Error("newer version should be stale")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// versionTTL is how long `bd --version` / `gt --version` results are
+// reused by /health/deep and /api/diagnostics before being refreshed.
+const versionTTL = 5 * time.Minute
+
+var versionedTools = []string{"bd", "gt"}
+
+// toolVersion is one tool's entry in the version cache.
+type toolVersion struct {
+	Available bool   `json:"available"`
+	Version   string `json:"version,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+var versionCache struct {
+	mu         sync.Mutex
+	versions   map[string]toolVersion
+	fetched    time.Time
+	refreshing *versionRefresh
+}
+
+// versionRefresh is a running `<tool> --version` round; versions is set
+// before done is closed.
+type versionRefresh struct {
+	done     chan struct{}
+	versions map[string]toolVersion
+}
+
+// toolVersions returns the cached versions of bd and gt. Once the cache is
+// older than versionTTL it is refreshed in the background and the last
+// value is served meanwhile; only a caller with nothing cached yet waits
+// for `<tool> --version`. The lock is never held while the tools run.
+func toolVersions() map[string]toolVersion {
+	versionCache.mu.Lock()
+	versions := versionCache.versions
+	if versions != nil && time.Since(versionCache.fetched) < versionTTL {
+		versionCache.mu.Unlock()
+		return versions
+	}
+	ref := versionCache.refreshing
+	if ref == nil {
+		ref = &versionRefresh{done: make(chan struct{})}
+		versionCache.refreshing = ref
+		go refreshToolVersions(ref)
+	}
+	versionCache.mu.Unlock()
+
+	if versions != nil {
+		return versions
+	}
+	<-ref.done
+	return ref.versions
+}
+
+// refreshToolVersions runs `<tool> --version` for each tool in parallel
+// and stores the result in ref and the cache.
+func refreshToolVersions(ref *versionRefresh) {
+	versions := make(map[string]toolVersion, len(versionedTools))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, tool := range versionedTools {
+		wg.Add(1)
+		go func(tool string) {
+			defer wg.Done()
+			v := toolVersion{Available: true}
+			data, err := execCmd(tool, []string{"--version"}, nil)
+			if err != nil {
+				v = toolVersion{Error: err.Error()}
+			} else {
+				// Plain-text output comes back from execCmd as a JSON string.
+				var text string
+				if json.Unmarshal(data, &text) != nil {
+					text = string(data)
+				}
+				v.Version = strings.TrimSpace(text)
+			}
+			mu.Lock()
+			versions[tool] = v
+			mu.Unlock()
+		}(tool)
+	}
+	wg.Wait()
+
+	ref.versions = versions
+	versionCache.mu.Lock()
+	versionCache.versions = versions
+	versionCache.fetched = time.Now()
+	versionCache.refreshing = nil
+	versionCache.mu.Unlock()
+	close(ref.done)
+}
+
+// invalidateToolCaches forgets cached tool versions, PATH lookups and
+// direct-read schema checks, so an upgraded or newly installed bd/gt is
+// noticed right away.
+func invalidateToolCaches() {
+	directSchema.Clear()
+
+	versionCache.mu.Lock()
+	versionCache.versions = nil
+	versionCache.mu.Unlock()
+
+	binaryCache.mu.Lock()
+	binaryCache.checked = time.Time{}
+	binaryCache.mu.Unlock()
+}
+
+// invalidateOnHUP clears the tool caches whenever the process gets SIGHUP.
+func invalidateOnHUP(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-hup:
+				log.Printf("SIGHUP: clearing cached bd/gt versions")
+				invalidateToolCaches()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
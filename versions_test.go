@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthDeepCachesVersions(t *testing.T) {
+	origRoot := townRoot
+	defer func() { townRoot = origRoot }()
+	townRoot = t.TempDir()
+	invalidateToolCaches()
+	t.Cleanup(invalidateToolCaches)
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeBin(t, "bd", `echo "$1" >> `+calls+`
+echo "bd version 0.42.0"
+`)
+	fakeBin(t, "gt", `echo "gt version 1.2.3"`)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handleHealthDeep(w, httptest.NewRequest("GET", "/health/deep", nil))
+		if w.Code != 200 {
+			t.Fatalf("health/deep status = %d, body: %s", w.Code, w.Body)
+		}
+		var result struct {
+			Versions map[string]toolVersion `json:"versions"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if v := result.Versions["bd"]; !v.Available || v.Version != "bd version 0.42.0" {
+			t.Errorf("bd version = %+v", v)
+		}
+	}
+	data, _ := os.ReadFile(calls)
+	if n := strings.Count(string(data), "--version"); n != 1 {
+		t.Errorf("bd --version ran %d times, want 1", n)
+	}
+
+	invalidateToolCaches()
+	toolVersions()
+	data, _ = os.ReadFile(calls)
+	if n := strings.Count(string(data), "--version"); n != 2 {
+		t.Errorf("after invalidation bd --version ran %d times total, want 2", n)
+	}
+}
+
+func TestHealthDeepMissingTool(t *testing.T) {
+	origRoot := townRoot
+	defer func() { townRoot = origRoot }()
+	townRoot = t.TempDir()
+	invalidateToolCaches()
+	t.Cleanup(invalidateToolCaches)
+
+	fakeBin(t, "gt", `echo "gt version 1.2.3"`)
+	t.Setenv("PATH", filepath.SplitList(os.Getenv("PATH"))[0])
+
+	w := httptest.NewRecorder()
+	handleHealthDeep(w, httptest.NewRequest("GET", "/health/deep", nil))
+	if w.Code != 503 || !strings.Contains(w.Body.String(), "bd unavailable") {
+		t.Errorf("missing bd = %d %s, want 503 naming bd", w.Code, w.Body)
+	}
+}
+
+func TestHealthRunsNoTools(t *testing.T) {
+	invalidateToolCaches()
+	t.Cleanup(invalidateToolCaches)
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeBin(t, "bd", `echo "$1" >> `+calls)
+	fakeBin(t, "gt", `echo "$1" >> `+calls)
+
+	w := httptest.NewRecorder()
+	handleHealth(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != 200 {
+		t.Fatalf("health status = %d", w.Code)
+	}
+	if _, err := os.Stat(calls); err == nil {
+		t.Error("/health ran bd or gt")
+	}
+}
+
+func TestToolVersionsServesStaleWhileRefreshing(t *testing.T) {
+	invalidateToolCaches()
+	t.Cleanup(invalidateToolCaches)
+	fakeBin(t, "bd", "sleep 1; echo 'bd version 2'")
+	fakeBin(t, "gt", "echo 'gt version 2'")
+
+	versionCache.mu.Lock()
+	versionCache.versions = map[string]toolVersion{"bd": {Available: true, Version: "bd version 1"}}
+	versionCache.fetched = time.Now().Add(-2 * versionTTL)
+	versionCache.mu.Unlock()
+
+	start := time.Now()
+	if v := toolVersions()["bd"].Version; v != "bd version 1" {
+		t.Errorf("stale cache served %q, want the old version", v)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("a stale cache waited %v for bd", d)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for toolVersions()["bd"].Version != "bd version 2" {
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh never stored the new version")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prefixMu guards replacing prefixMap at runtime. The map itself is never
+// mutated after it is built, so readers only need the lock to load it.
+var prefixMu sync.RWMutex
+
+func currentPrefixMap() map[string]string {
+	prefixMu.RLock()
+	defer prefixMu.RUnlock()
+	return prefixMap
+}
+
+// reloadPrefixMap rebuilds prefix resolution after routes or rigs change.
+func reloadPrefixMap() {
+	m := buildPrefixMap()
+	prefixMu.Lock()
+	prefixMap = m
+	prefixMu.Unlock()
+	beadCache.clear()
+	directSchema.Clear()
+}
+
+// watchStatus describes the active change-detection mechanism for
+// /api/diagnostics.
+type watchStatus struct {
+	Requested string `json:"requested"`
+	Active    string `json:"active"`
+	PollMs    int    `json:"pollMs,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+var watchState = watchStatus{Requested: "poll", Active: "none"}
+
+// startWatcher starts change detection for routes.jsonl and the rigs'
+// beads.db files according to cfg.WatchMode: "poll" (the default) or
+// "off". Rigradar has no native (inotify/FSEvents) watcher.
+func startWatcher(ctx context.Context, cfg Config) {
+	mode := cfg.WatchMode
+	if mode == "" {
+		mode = "poll"
+	}
+	watchState = watchStatus{Requested: mode, Active: "none"}
+
+	switch mode {
+	case "poll":
+		interval := time.Duration(cfg.WatchPollMs) * time.Millisecond
+		if interval <= 0 {
+			interval = 2 * time.Second
+		}
+		watchState.Active = "poll"
+		watchState.PollMs = int(interval.Milliseconds())
+		go pollForChanges(ctx, interval, watchSignature())
+	case "off":
+	default:
+		watchState.Note = fmt.Sprintf("unknown watchMode %q", mode)
+		log.Printf("warning: unknown watchMode %q, not watching for changes", mode)
+	}
+}
+
+func pollForChanges(ctx context.Context, interval time.Duration, last string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sig := watchSignature()
+		if sig == last {
+			continue
+		}
+		last = sig
+		log.Printf("watch: routes, prefixOverrides or beads databases changed, rebuilding prefix map")
+		reloadPrefixMap()
+	}
+}
+
+// watchSignature summarises the mtimes of routes.jsonl, .rigradarignore,
+// the town root (new rig directories) and every known beads.db, plus the
+// prefixOverrides in config.json. Missing files count as a zero mtime, so
+// appearing and disappearing are changes too. Only the overrides are taken
+// from config.json, so saving unrelated settings doesn't rebuild the map.
+func watchSignature() string {
+	paths := []string{
+		filepath.Join(townRoot, ".beads", "routes.jsonl"),
+		filepath.Join(townRoot, ".rigradarignore"),
+		townRoot,
+	}
+	for _, dir := range beadDirs() {
+		paths = append(paths, filepath.Join(dir, "beads.db"))
+	}
+
+	var b strings.Builder
+	for _, p := range paths {
+		var mtime int64
+		if info, err := os.Stat(p); err == nil {
+			mtime = info.ModTime().UnixNano()
+		}
+		fmt.Fprintf(&b, "%s=%d;", p, mtime)
+	}
+	configMu.RLock()
+	overrides := loadConfig().PrefixOverrides
+	configMu.RUnlock()
+	// fmt prints maps in key order, so equal overrides print the same.
+	fmt.Fprintf(&b, "prefixOverrides=%v", overrides)
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPollWatcherRebuildsPrefixMap(t *testing.T) {
+	origRoot, origMap, origState := townRoot, prefixMap, watchState
+	defer func() { townRoot, prefixMap, watchState = origRoot, origMap, origState }()
+
+	townRoot = t.TempDir()
+	os.MkdirAll(filepath.Join(townRoot, ".beads"), 0755)
+	reloadPrefixMap()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startWatcher(ctx, Config{WatchMode: "poll", WatchPollMs: 20})
+	if watchState.Active != "poll" {
+		t.Fatalf("watch active = %q, want poll", watchState.Active)
+	}
+
+	os.WriteFile(filepath.Join(townRoot, ".beads", "routes.jsonl"),
+		[]byte(`{"prefix":"nw-","path":"newrig"}`+"\n"), 0644)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := currentPrefixMap()["nw"]; ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("poll watcher did not pick up the new route")
+}
+
+func TestStartWatcherDefaultsToPoll(t *testing.T) {
+	origState := watchState
+	defer func() { watchState = origState }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startWatcher(ctx, Config{})
+	if watchState.Requested != "poll" || watchState.Active != "poll" || watchState.PollMs != 2000 {
+		t.Errorf("default watch state = %+v, want poll every 2000ms", watchState)
+	}
+
+	startWatcher(ctx, Config{WatchMode: "off"})
+	if watchState.Active != "none" {
+		t.Errorf("watchMode off: active = %q, want none", watchState.Active)
+	}
+}
+
+func TestPollWatcherPicksUpPrefixOverrides(t *testing.T) {
+	origRoot, origMap, origState, origPath := townRoot, prefixMap, watchState, configPath
+	defer func() { townRoot, prefixMap, watchState, configPath = origRoot, origMap, origState, origPath }()
+
+	townRoot = t.TempDir()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	saveConfig(loadConfig())
+	reloadPrefixMap()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startWatcher(ctx, Config{WatchMode: "poll", WatchPollMs: 20})
+
+	cfg := loadConfig()
+	cfg.PrefixOverrides = map[string]string{"ov": "/srv/beads/ov/.beads"}
+	saveConfig(cfg)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if currentPrefixMap()["ov"] == "/srv/beads/ov/.beads" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("poll watcher did not pick up the new prefix override")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+func handleWatched(w http.ResponseWriter, r *http.Request) {
+	configMu.RLock()
+	ids := loadConfig().WatchedBeads
+	configMu.RUnlock()
+
+	// Each entry is the bead as bd shows it, or {id, error} when it
+	// cannot be fetched (e.g. deleted since it was watched).
+	beads := make([]map[string]any, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			bead, err := showBead(r, id)
+			if err != nil {
+				bead = map[string]any{"id": id, "error": err.Error()}
+			}
+			beads[i] = bead
+		}(i, id)
+	}
+	wg.Wait()
+	sendJSON(w, beads, http.StatusOK)
+}
+
+// handleWatchBead adds (add=true) or removes a bead id from
+// Config.WatchedBeads and returns the updated list. It writes config.json,
+// so ReadOnly turns it off like the other write endpoints. Ids are checked
+// because /api/watched passes them to bd show.
+func handleWatchBead(add bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !validBeadID(id) {
+			sendError(w, fmt.Sprintf("invalid bead id %q", id), http.StatusBadRequest)
+			return
+		}
+
+		configMu.Lock()
+		cfg := loadConfig()
+		if cfg.ReadOnly {
+			configMu.Unlock()
+			sendError(w, "watched beads cannot be changed while readOnly is set", http.StatusForbidden)
+			return
+		}
+		i := slices.Index(cfg.WatchedBeads, id)
+		switch {
+		case add && i < 0:
+			cfg.WatchedBeads = append(cfg.WatchedBeads, id)
+		case !add && i >= 0:
+			cfg.WatchedBeads = slices.Delete(cfg.WatchedBeads, i, i+1)
+		}
+		err := saveConfig(cfg)
+		configMu.Unlock()
+
+		if err != nil {
+			sendError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		watched := cfg.WatchedBeads
+		if watched == nil {
+			watched = []string{}
+		}
+		sendJSON(w, watched, http.StatusOK)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatchedBeads(t *testing.T) {
+	origPath, origMap, origRoot := configPath, prefixMap, townRoot
+	defer func() { configPath, prefixMap, townRoot = origPath, origMap, origRoot }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	townRoot = t.TempDir()
+	prefixMap = map[string]string{"hq": filepath.Join(townRoot, ".beads")}
+
+	fakeBin(t, "bd", `case "$2" in
+hq-1) echo '{"id":"hq-1","status":"open"}' ;;
+*) echo "not found" >&2; exit 1 ;;
+esac
+`)
+
+	ts := httptest.NewServer(buildHandler())
+	defer ts.Close()
+	post := func(path string) []string {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var ids []string
+		json.NewDecoder(resp.Body).Decode(&ids)
+		return ids
+	}
+
+	post("/api/watched/hq-1/add")
+	post("/api/watched/hq-gone/add")
+	if ids := post("/api/watched/hq-1/add"); len(ids) != 2 {
+		t.Errorf("adding twice should not duplicate: %v", ids)
+	}
+	if got := loadConfig().WatchedBeads; len(got) != 2 {
+		t.Errorf("watched beads not persisted: %v", got)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/watched")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var beads []map[string]any
+	json.NewDecoder(resp.Body).Decode(&beads)
+	resp.Body.Close()
+	if len(beads) != 2 || beads[0]["status"] != "open" || beads[1]["error"] == nil {
+		t.Errorf("watched = %v, want hq-1 open and hq-gone with an error", beads)
+	}
+
+	if ids := post("/api/watched/hq-gone/remove"); len(ids) != 1 || ids[0] != "hq-1" {
+		t.Errorf("after remove = %v, want [hq-1]", ids)
+	}
+
+	status := func(path string) int {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// An id bd would take for a flag is never stored.
+	if code := status("/api/watched/--help/add"); code != 400 {
+		t.Errorf("adding --help status = %d, want 400", code)
+	}
+	cfg := loadConfig()
+	cfg.ReadOnly = true
+	saveConfig(cfg)
+	if code := status("/api/watched/hq-2/add"); code != 403 {
+		t.Errorf("adding while read-only status = %d, want 403", code)
+	}
+	if got := loadConfig().WatchedBeads; len(got) != 1 || got[0] != "hq-1" {
+		t.Errorf("rejected adds changed the list: %v", got)
+	}
+}